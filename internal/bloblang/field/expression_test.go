@@ -467,3 +467,24 @@ func TestLegacyExpressions(t *testing.T) {
 		})
 	}
 }
+
+func TestExpressionSharedState(t *testing.T) {
+	e := NewExpression(NewQueryResolver(func() query.Function {
+		fn, err := query.InitFunction("content")
+		require.NoError(t, err)
+		fn, err = query.InitMethod("bloom_add", fn, "ids")
+		require.NoError(t, err)
+		return fn
+	}()))
+
+	msg := message.New([][]byte{
+		[]byte(`foo`),
+		[]byte(`bar`),
+		[]byte(`foo`),
+	})
+
+	assert.Equal(t, "false", e.String(0, msg))
+	assert.Equal(t, "false", e.String(1, msg))
+	assert.Equal(t, "true", e.String(2, msg))
+	assert.Equal(t, "true", string(e.Bytes(0, msg)))
+}
@@ -37,13 +37,14 @@ func (s StaticResolver) ResolveBytes(index int, msg Message, escaped, legacy boo
 // QueryResolver executes a query and returns a string representation of the
 // result.
 type QueryResolver struct {
-	fn query.Function
+	fn     query.Function
+	shared *query.SharedState
 }
 
 // NewQueryResolver creates a field query resolver that returns the result of a
 // query function.
 func NewQueryResolver(fn query.Function) *QueryResolver {
-	return &QueryResolver{fn}
+	return &QueryResolver{fn, query.NewSharedState()}
 }
 
 // ResolveString returns a string.
@@ -56,6 +57,7 @@ func (q QueryResolver) ResolveString(index int, msg Message, escaped, legacy boo
 		MsgBatch: msg,
 		Legacy:   legacy,
 		NewMsg:   msg.Get(index),
+		Shared:   q.shared,
 	}.WithValueFunc(func() *interface{} {
 		if jObj, err := msg.Get(index).JSON(); err == nil {
 			return &jObj
@@ -74,6 +76,7 @@ func (q QueryResolver) ResolveBytes(index int, msg Message, escaped, legacy bool
 		MsgBatch: msg,
 		Legacy:   legacy,
 		NewMsg:   msg.Get(index),
+		Shared:   q.shared,
 	}.WithValueFunc(func() *interface{} {
 		if jObj, err := msg.Get(index).JSON(); err == nil {
 			return &jObj
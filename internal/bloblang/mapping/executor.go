@@ -65,6 +65,7 @@ type Executor struct {
 	input      []rune
 	maps       map[string]query.Function
 	statements []Statement
+	shared     *query.SharedState
 }
 
 // NewExecutor initialises a new mapping executor from a map of query functions,
@@ -72,7 +73,7 @@ type Executor struct {
 // is an optional slice pointing to the parsed expression that created the
 // executor.
 func NewExecutor(annotation string, input []rune, maps map[string]query.Function, statements ...Statement) *Executor {
-	return &Executor{annotation, input, maps, statements}
+	return &Executor{annotation, input, maps, statements, query.NewSharedState()}
 }
 
 // Annotation returns a string annotation that describes the mapping executor.
@@ -155,14 +156,17 @@ func (e *Executor) mapPart(appendTo types.Part, index int, reference Message) (t
 	}
 
 	vars := map[string]interface{}{}
+	execShared := query.NewSharedState()
 
 	for _, stmt := range e.statements {
 		res, err := stmt.query.Exec(query.FunctionContext{
-			Maps:     e.maps,
-			Vars:     vars,
-			Index:    index,
-			MsgBatch: reference,
-			NewMsg:   newPart,
+			Maps:       e.maps,
+			Vars:       vars,
+			Index:      index,
+			MsgBatch:   reference,
+			NewMsg:     newPart,
+			Shared:     e.shared,
+			ExecShared: execShared,
 		}.WithValueFunc(lazyValue))
 		if err != nil {
 			var line int
@@ -246,6 +250,12 @@ func (e *Executor) Exec(ctx query.FunctionContext) (interface{}, error) {
 	if stackCount > maxMapStacks {
 		return nil, fmt.Errorf("entering %v exceeded maximum allowed stacks of %v, this could be due to unbounded recursion", e.annotation, maxMapStacks)
 	}
+	if ctx.Shared == nil {
+		ctx.Shared = e.shared
+	}
+	if ctx.ExecShared == nil {
+		ctx.ExecShared = query.NewSharedState()
+	}
 
 	var newObj interface{} = query.Nothing(nil)
 	for _, stmt := range e.statements {
@@ -283,6 +293,12 @@ func (e *Executor) Exec(ctx query.FunctionContext) (interface{}, error) {
 
 // ExecOnto a provided assignment context.
 func (e *Executor) ExecOnto(ctx query.FunctionContext, onto AssignmentContext) error {
+	if ctx.Shared == nil {
+		ctx.Shared = e.shared
+	}
+	if ctx.ExecShared == nil {
+		ctx.ExecShared = query.NewSharedState()
+	}
 	for _, stmt := range e.statements {
 		res, err := stmt.query.Exec(ctx)
 		if err != nil {
@@ -130,6 +130,7 @@ var (
 	MethodCategoryCoercion       MethodCategory = "Type Coercion"
 	MethodCategoryParsing        MethodCategory = "Parsing"
 	MethodCategoryObjectAndArray MethodCategory = "Object & Array Manipulation"
+	MethodCategoryDeduplication  MethodCategory = "Deduplication"
 	MethodCategoryDeprecated     MethodCategory = "Deprecated"
 	MethodCategoryPlugin         MethodCategory = "Plugin"
 )
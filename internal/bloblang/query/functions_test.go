@@ -1,12 +1,20 @@
 package query
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"math"
 	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/Jeffail/benthos/v3/lib/message"
+	"github.com/Jeffail/benthos/v3/lib/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -112,6 +120,146 @@ func TestFunctions(t *testing.T) {
 				}},
 			},
 		},
+		"check is_business_day weekday": {
+			input:  mustFunc("is_business_day", "2021-10-15"),
+			output: true,
+		},
+		"check is_business_day weekend": {
+			input:  mustFunc("is_business_day", "2021-10-16"),
+			output: false,
+		},
+		"check is_business_day holiday": {
+			input:  mustFunc("is_business_day", "2021-12-24", []interface{}{"2021-12-24"}),
+			output: false,
+		},
+		"check is_business_day empty holidays": {
+			input:  mustFunc("is_business_day", "2021-12-24", []interface{}{}),
+			output: true,
+		},
+		"check is_business_day timestamp": {
+			input:  mustFunc("is_business_day", "2021-10-16T01:00:00+02:00"),
+			output: false,
+		},
+		"check next_business_day across weekend": {
+			input:  mustFunc("next_business_day", "2021-10-15"),
+			output: "2021-10-18",
+		},
+		"check next_business_day from weekend": {
+			input:  mustFunc("next_business_day", "2021-10-16", []interface{}{}),
+			output: "2021-10-18",
+		},
+		"check next_business_day onto holiday": {
+			input:  mustFunc("next_business_day", "2021-12-23", []interface{}{"2021-12-24", "2021-12-27"}),
+			output: "2021-12-28",
+		},
+		"check next_business_day timestamp": {
+			input:  mustFunc("next_business_day", "2021-10-15T16:30:00-04:00", []interface{}{"2021-10-18"}),
+			output: "2021-10-19T16:30:00-04:00",
+		},
+		"check next_business_day dynamic": {
+			input: mustFunc("next_business_day", mustFunc("json", "date"), mustFunc("json", "holidays")),
+			messages: []easyMsg{
+				{content: `{"date":"2021-12-31","holidays":["2022-01-03"]}`},
+			},
+			output: "2022-01-04",
+		},
+		"check cron_next step": {
+			input:  mustFunc("cron_next", "0 */5 * * * *", "2021-10-15T09:31:12Z"),
+			output: "2021-10-15T09:35:00Z",
+		},
+		"check cron_next at match": {
+			input:  mustFunc("cron_next", "0 */5 * * * *", "2021-10-15T09:35:00Z"),
+			output: "2021-10-15T09:35:00Z",
+		},
+		"check cron_next at match with fraction": {
+			input:  mustFunc("cron_next", "0 */5 * * * *", "2021-10-15T09:35:00.5Z"),
+			output: "2021-10-15T09:40:00Z",
+		},
+		"check cron_next five fields range": {
+			input:  mustFunc("cron_next", "0 9-17 * * *", "2021-10-15T17:30:00Z"),
+			output: "2021-10-16T09:00:00Z",
+		},
+		"check cron_next list": {
+			input:  mustFunc("cron_next", "15,45 * * * *", "2021-10-15T09:16:00Z"),
+			output: "2021-10-15T09:45:00Z",
+		},
+		"check cron_next day of week range": {
+			input:  mustFunc("cron_next", "0 9 * * MON-FRI", "2021-10-15T10:00:00Z"),
+			output: "2021-10-18T09:00:00Z",
+		},
+		"check cron_next descriptor": {
+			input:  mustFunc("cron_next", "@daily", "2021-10-15T10:00:00Z"),
+			output: "2021-10-16T00:00:00Z",
+		},
+		"check cron_next timestamp timezone": {
+			input:  mustFunc("cron_next", "0 9 * * *", "2021-10-15T10:00:00+02:00"),
+			output: "2021-10-16T09:00:00+02:00",
+		},
+		"check cron_next expression timezone": {
+			input:  mustFunc("cron_next", "TZ=America/New_York 0 9 * * *", "2021-10-15T12:00:00Z"),
+			output: "2021-10-15T13:00:00Z",
+		},
+		"check cron_next dynamic": {
+			input: mustFunc("cron_next", "0 0 1 * *", mustFunc("json", "after")),
+			messages: []easyMsg{
+				{content: `{"after":"2021-10-15T12:00:00Z"}`},
+			},
+			output: "2021-11-01T00:00:00Z",
+		},
+		"check meta function all": {
+			input: mustFunc("meta"),
+			output: map[string]interface{}{
+				"foo": "foobar",
+				"bar": "barbaz",
+			},
+			messages: []easyMsg{
+				{content: "", meta: map[string]string{
+					"foo":   "foobar",
+					"bar":   "barbaz",
+					"empty": "",
+				}},
+			},
+		},
+		"check meta function all empty": {
+			input:  mustFunc("meta"),
+			output: map[string]interface{}{},
+			messages: []easyMsg{
+				{content: ""},
+			},
+		},
+		"check meta function key alongside all": {
+			input:  mustFunc("meta", "bar"),
+			output: "barbaz",
+			messages: []easyMsg{
+				{content: "", meta: map[string]string{
+					"foo": "foobar",
+					"bar": "barbaz",
+				}},
+			},
+		},
+		"check content_length empty": {
+			input:  mustFunc("content_length"),
+			output: int64(0),
+			messages: []easyMsg{
+				{content: ""},
+			},
+		},
+		"check content_length multi-byte": {
+			input:  mustFunc("content_length"),
+			output: int64(9),
+			messages: []easyMsg{
+				{content: "日本語"},
+			},
+		},
+		"check content_length with index": {
+			input:  mustFunc("content_length"),
+			output: int64(6),
+			index:  1,
+			messages: []easyMsg{
+				{content: "foo"},
+				{content: "barbaz"},
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -227,6 +375,43 @@ func TestEnvFunction(t *testing.T) {
 	assert.Equal(t, "foobar", res)
 }
 
+func TestEnvFunctionDefault(t *testing.T) {
+	key := "BENTHOS_TEST_BLOBLANG_FUNCTION_DEFAULT"
+	os.Unsetenv(key)
+
+	tests := []struct {
+		name   string
+		set    bool
+		value  string
+		args   []interface{}
+		output interface{}
+	}{
+		{name: "unset without default", args: []interface{}{key}, output: ""},
+		{name: "unset with default", args: []interface{}{key, "fallback"}, output: "fallback"},
+		{name: "set with default", set: true, value: "foobar", args: []interface{}{key, "fallback"}, output: "foobar"},
+		{name: "set empty with default", set: true, value: "", args: []interface{}{key, "fallback"}, output: ""},
+	}
+
+	for _, test := range tests {
+		if test.set {
+			os.Setenv(key, test.value)
+		} else {
+			os.Unsetenv(key)
+		}
+
+		e, err := InitFunction("env", test.args...)
+		require.NoError(t, err, test.name)
+
+		res, err := e.Exec(FunctionContext{})
+		require.NoError(t, err, test.name)
+		assert.Equal(t, test.output, res, test.name)
+	}
+	os.Unsetenv(key)
+
+	_, err := InitFunction("env", key, int64(10))
+	require.Error(t, err)
+}
+
 func TestRandomInt(t *testing.T) {
 	e, err := InitFunction("random_int")
 	require.Nil(t, err)
@@ -350,3 +535,1084 @@ func TestRandomIntDynamicParallel(t *testing.T) {
 	close(startChan)
 	wg.Wait()
 }
+
+func TestWeightedRandom(t *testing.T) {
+	weights := map[string]interface{}{
+		"a": 0.7,
+		"b": 0.2,
+		"c": int64(10),
+	}
+
+	e, err := InitFunction("weighted_random", weights, int64(1))
+	require.NoError(t, err)
+
+	tallies := map[string]int{}
+	iterations := 20000
+	for i := 0; i < iterations; i++ {
+		res, err := e.Exec(FunctionContext{})
+		require.NoError(t, err)
+		tallies[res.(string)]++
+	}
+
+	total := 10.9
+	for k, w := range map[string]float64{"a": 0.7, "b": 0.2, "c": 10} {
+		assert.InDelta(t, w/total, float64(tallies[k])/float64(iterations), 0.01, k)
+	}
+}
+
+func TestWeightedRandomDeterministic(t *testing.T) {
+	weights := map[string]interface{}{"a": 1.0, "b": 1.0, "c": 1.0}
+
+	eA, err := InitFunction("weighted_random", weights, int64(5))
+	require.NoError(t, err)
+	eB, err := InitFunction("weighted_random", weights, int64(5))
+	require.NoError(t, err)
+
+	var resA, resB []interface{}
+	for i := 0; i < 50; i++ {
+		v, err := eA.Exec(FunctionContext{})
+		require.NoError(t, err)
+		resA = append(resA, v)
+
+		v, err = eB.Exec(FunctionContext{})
+		require.NoError(t, err)
+		resB = append(resB, v)
+	}
+	assert.Equal(t, resA, resB)
+}
+
+func TestWeightedRandomDynamicSeed(t *testing.T) {
+	weights := map[string]interface{}{"a": 1.0, "b": 1.0, "c": 1.0, "d": 1.0}
+
+	sequence := func(ids ...interface{}) []interface{} {
+		t.Helper()
+		e, err := InitFunction("weighted_random", weights, NewFieldFunction("id"))
+		require.NoError(t, err)
+
+		var res []interface{}
+		for _, id := range ids {
+			v, err := e.Exec(FunctionContext{}.WithValue(map[string]interface{}{"id": id}))
+			require.NoError(t, err)
+			res = append(res, v)
+		}
+		return res
+	}
+
+	// The seed is only resolved during the first execution.
+	assert.Equal(t, sequence("user-1", "user-1", "user-1"), sequence("user-1", "user-2", "user-3"))
+	assert.Equal(t, sequence(int64(5), int64(5), int64(5)), sequence("5", "6", "7"))
+
+	static, err := InitFunction("weighted_random", weights, "user-1")
+	require.NoError(t, err)
+
+	var staticRes []interface{}
+	for i := 0; i < 3; i++ {
+		v, err := static.Exec(FunctionContext{})
+		require.NoError(t, err)
+		staticRes = append(staticRes, v)
+	}
+	assert.Equal(t, staticRes, sequence("user-1", "user-1", "user-1"))
+
+	e, err := InitFunction("weighted_random", weights, NewFieldFunction("id"))
+	require.NoError(t, err)
+
+	_, err = e.Exec(FunctionContext{}.WithValue(map[string]interface{}{"id": []interface{}{"nope"}}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to seed random number generator")
+}
+
+func TestWeightedRandomErrors(t *testing.T) {
+	_, err := InitFunction("weighted_random", map[string]interface{}{"a": 1.0, "b": 0.0})
+	require.EqualError(t, err, "weight of key 'b' must be greater than zero, got 0")
+
+	_, err = InitFunction("weighted_random", map[string]interface{}{"a": -0.5})
+	require.EqualError(t, err, "weight of key 'a' must be greater than zero, got -0.5")
+
+	_, err = InitFunction("weighted_random", map[string]interface{}{})
+	require.EqualError(t, err, "weights object must not be empty")
+
+	_, err = InitFunction("weighted_random", "nope")
+	require.EqualError(t, err, `expected object value, got string ("nope")`)
+
+	e, err := InitFunction("weighted_random", NewFieldFunction("weights"))
+	require.NoError(t, err)
+
+	_, err = e.Exec(FunctionContext{}.WithValue(map[string]interface{}{
+		"weights": map[string]interface{}{"a": "heavy"},
+	}))
+	require.EqualError(t, err, `weight of key 'a': expected number value, got string ("heavy")`)
+}
+
+func TestBusinessDayErrors(t *testing.T) {
+	_, err := InitFunction("is_business_day", "2021-10-16", []interface{}{"nope"})
+	require.EqualError(t, err, `holiday 0: parsing time "nope" as "2006-01-02T15:04:05.999999999Z07:00": cannot parse "nope" as "2006"`)
+
+	_, err = InitFunction("next_business_day", "2021-10-16", "2021-10-18")
+	require.EqualError(t, err, `expected array value, got string ("2021-10-18")`)
+
+	e, err := InitFunction("next_business_day", NewFieldFunction("date"))
+	require.NoError(t, err)
+
+	_, err = e.Exec(FunctionContext{}.WithValue(map[string]interface{}{
+		"date": true,
+	}))
+	require.EqualError(t, err, `expected number or string value, got bool (true)`)
+}
+
+func TestCronNextErrors(t *testing.T) {
+	_, err := InitFunction("cron_next", "not a cron", "2021-10-15T12:00:00Z")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse cron expression")
+
+	_, err = InitFunction("cron_next", "61 * * * *", "2021-10-15T12:00:00Z")
+	require.Error(t, err)
+
+	e, err := InitFunction("cron_next", "* * * * *", NewFieldFunction("after"))
+	require.NoError(t, err)
+
+	_, err = e.Exec(FunctionContext{}.WithValue(map[string]interface{}{
+		"after": "nope",
+	}))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `cannot parse "nope"`)
+}
+
+func TestSemverCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		exp  int64
+	}{
+		{a: "1.0.0", b: "1.0.0", exp: 0},
+		{a: "1.0.0", b: "2.0.0", exp: -1},
+		{a: "2.1.0", b: "2.0.9", exp: 1},
+		{a: "1.10.0", b: "1.9.0", exp: 1},
+		{a: "v1.2.3", b: "1.2.3", exp: 0},
+		{a: "1.0.0-alpha", b: "1.0.0", exp: -1},
+		{a: "1.0.0", b: "1.0.0-rc.1", exp: 1},
+		{a: "1.0.0-alpha", b: "1.0.0-alpha.1", exp: -1},
+		{a: "1.0.0-alpha.1", b: "1.0.0-alpha.beta", exp: -1},
+		{a: "1.0.0-alpha.beta", b: "1.0.0-beta", exp: -1},
+		{a: "1.0.0-beta.2", b: "1.0.0-beta.11", exp: -1},
+		{a: "1.0.0-beta.11", b: "1.0.0-rc.1", exp: -1},
+		{a: "1.0.0+build.1", b: "1.0.0+build.2", exp: 0},
+		{a: "1.0.0-rc.1+build.1", b: "1.0.0-rc.1", exp: 0},
+	}
+
+	for _, test := range tests {
+		e, err := InitFunction("semver_compare", test.a, test.b)
+		require.NoError(t, err)
+
+		res, err := e.Exec(FunctionContext{})
+		require.NoError(t, err)
+		assert.Equal(t, test.exp, res, "%v vs %v", test.a, test.b)
+	}
+
+	_, err := InitFunction("semver_compare", "1.0", "1.0.0")
+	require.EqualError(t, err, "failed to parse '1.0' as a semantic version")
+}
+
+func TestParseAcceptHeader(t *testing.T) {
+	mediaRange := func(typ, subtype string, q float64) interface{} {
+		return map[string]interface{}{"type": typ, "subtype": subtype, "q": q}
+	}
+
+	tests := []struct {
+		header string
+		exp    []interface{}
+	}{
+		{
+			header: "",
+			exp:    []interface{}{},
+		},
+		{
+			header: "*/*",
+			exp:    []interface{}{mediaRange("*", "*", 1)},
+		},
+		{
+			header: "text/*;q=0.5, TEXT/HTML, */*;q=0",
+			exp: []interface{}{
+				mediaRange("text", "html", 1),
+				mediaRange("text", "*", 0.5),
+				mediaRange("*", "*", 0),
+			},
+		},
+		{
+			header: "application/xml;q=0.9, application/json;q=0.9;charset=utf-8, image/webp",
+			exp: []interface{}{
+				mediaRange("image", "webp", 1),
+				mediaRange("application", "xml", 0.9),
+				mediaRange("application", "json", 0.9),
+			},
+		},
+		{
+			header: "text, /html, text/, text/html;q=nope, text/plain;q=1.5, ,text/csv;q=0.2",
+			exp: []interface{}{
+				mediaRange("text", "csv", 0.2),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		e, err := InitFunction("parse_accept_header", test.header)
+		require.NoError(t, err)
+
+		res, err := e.Exec(FunctionContext{})
+		require.NoError(t, err)
+		assert.Equal(t, test.exp, res, test.header)
+	}
+}
+
+func TestGeohash(t *testing.T) {
+	points := [][2]float64{
+		{57.64911, 10.40744},
+		{-33.8688, 151.2093},
+		{40.7128, -74.0060},
+		{0, 0},
+		{-90, -180},
+		{90, 180},
+	}
+
+	for _, p := range points {
+		for precision := int64(1); precision <= 12; precision++ {
+			e, err := InitFunction("geohash_encode", p[0], p[1], precision)
+			require.NoError(t, err)
+
+			hash, err := e.Exec(FunctionContext{})
+			require.NoError(t, err)
+			require.Len(t, hash, int(precision))
+
+			m, err := InitMethod("geohash_decode", NewLiteralFunction("", hash))
+			require.NoError(t, err)
+
+			res, err := m.Exec(FunctionContext{})
+			require.NoError(t, err)
+
+			decoded := res.(map[string]interface{})
+			assert.LessOrEqual(t, math.Abs(decoded["lat"].(float64)-p[0]), decoded["lat_err"].(float64), "%v %v", p, hash)
+			assert.LessOrEqual(t, math.Abs(decoded["lon"].(float64)-p[1]), decoded["lon_err"].(float64), "%v %v", p, hash)
+		}
+	}
+
+	e, err := InitFunction("geohash_encode", 57.64911, 10.40744)
+	require.NoError(t, err)
+
+	hash, err := e.Exec(FunctionContext{})
+	require.NoError(t, err)
+	assert.Equal(t, "u4pruydqqvj8", hash)
+
+	for _, test := range []struct {
+		args []interface{}
+		err  string
+	}{
+		{args: []interface{}{91.0, 0.0}, err: "latitude must be between -90 and 90, got 91"},
+		{args: []interface{}{0.0, -180.5}, err: "longitude must be between -180 and 180, got -180.5"},
+		{args: []interface{}{0.0, 0.0, int64(0)}, err: "geohash precision must be between 1 and 12, got 0"},
+		{args: []interface{}{0.0, 0.0, int64(13)}, err: "geohash precision must be between 1 and 12, got 13"},
+	} {
+		_, err := InitFunction("geohash_encode", test.args...)
+		assert.EqualError(t, err, test.err)
+	}
+
+	for _, test := range []struct {
+		hash string
+		err  string
+	}{
+		{hash: "", err: "string literal: geohash must not be empty"},
+		{hash: "u4pa", err: "string literal: invalid geohash character 'a' at position 3"},
+	} {
+		m, err := InitMethod("geohash_decode", NewLiteralFunction("", test.hash))
+		require.NoError(t, err)
+
+		_, err = m.Exec(FunctionContext{})
+		assert.EqualError(t, err, test.err)
+	}
+}
+
+func TestParseBasicAuth(t *testing.T) {
+	for _, test := range []struct {
+		header     string
+		user, pass string
+		err        string
+	}{
+		{header: "Basic YWxhZGRpbjpvcGVuc2VzYW1l", user: "aladdin", pass: "opensesame"},
+		{header: "basic  YWxhZGRpbjpvcGVuc2VzYW1l ", user: "aladdin", pass: "opensesame"},
+		{header: "Basic dXNlcjpwYTpzczp3b3Jk", user: "user", pass: "pa:ss:word"},
+		{header: "Basic dXNlcjo=", user: "user", pass: ""},
+		{header: "Basic OnBhc3M=", user: "", pass: "pass"},
+		{header: "Bearer abc.def.ghi", err: "authorization header does not use the Basic scheme"},
+		{header: "", err: "authorization header does not use the Basic scheme"},
+		{header: "Basic ", err: "authorization header is missing credentials"},
+		{header: "Basic", err: "authorization header is missing credentials"},
+		{header: "Basic not*base64", err: "failed to decode credentials: illegal base64 data at input byte 3"},
+		{header: "Basic dXNlcg==", err: "credentials are missing a ':' separator"},
+	} {
+		e, err := InitFunction("parse_basic_auth", test.header)
+		if test.err != "" {
+			require.EqualError(t, err, test.err, test.header)
+			continue
+		}
+		require.NoError(t, err, test.header)
+
+		res, err := e.Exec(FunctionContext{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"user": test.user, "pass": test.pass}, res, test.header)
+	}
+}
+
+func TestBuildBasicAuth(t *testing.T) {
+	for _, test := range []struct {
+		user, pass string
+		exp        string
+	}{
+		{user: "aladdin", pass: "opensesame", exp: "Basic YWxhZGRpbjpvcGVuc2VzYW1l"},
+		{user: "user", pass: "pa:ss:word", exp: "Basic dXNlcjpwYTpzczp3b3Jk"},
+		{user: "", pass: "", exp: "Basic Og=="},
+		{user: "jürgen", pass: "p@ss w0rd!£", exp: "Basic asO8cmdlbjpwQHNzIHcwcmQhwqM="},
+	} {
+		e, err := InitFunction("build_basic_auth", test.user, test.pass)
+		require.NoError(t, err)
+
+		header, err := e.Exec(FunctionContext{})
+		require.NoError(t, err)
+		assert.Equal(t, test.exp, header)
+
+		e, err = InitFunction("parse_basic_auth", header)
+		require.NoError(t, err)
+
+		res, err := e.Exec(FunctionContext{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"user": test.user, "pass": test.pass}, res)
+	}
+
+	_, err := InitFunction("build_basic_auth", "us:er", "pass")
+	require.EqualError(t, err, "username must not contain a colon")
+}
+
+func TestCronDescribe(t *testing.T) {
+	for _, test := range []struct {
+		expr, exp string
+	}{
+		{expr: "* * * * *", exp: "every minute"},
+		{expr: "*/15 * * * *", exp: "every 15 minutes"},
+		{expr: "0 * * * *", exp: "every hour"},
+		{expr: "15 * * * *", exp: "at 15 minutes past the hour"},
+		{expr: "1 * * * *", exp: "at 1 minute past the hour"},
+		{expr: "0 */2 * * *", exp: "every 2 hours"},
+		{expr: "30 8,17 * * *", exp: "at 08:30 and 17:30"},
+		{expr: "0,15,30,45 9 * * *", exp: "at 09:00, 09:15, 09:30 and 09:45"},
+
+		// Ranges
+		{expr: "0-30 * * * *", exp: "at minutes 0 through 30 past the hour"},
+		{expr: "*/5 9-17 * * *", exp: "every 5 minutes, between 09:00 and 17:59"},
+		{expr: "0 9-17 * * *", exp: "every hour, between 09:00 and 17:59"},
+		{expr: "0 0 1-7 * *", exp: "at 00:00, on days 1 through 7 of the month"},
+		{expr: "0 0 * JAN-MAR *", exp: "at 00:00, in January through March"},
+
+		// Lists
+		{expr: "0,30 * * * *", exp: "at 0 and 30 minutes past the hour"},
+		{expr: "0-10,30 * * * *", exp: "at minutes 0 through 10 and 30 past the hour"},
+		{expr: "15,45 8-18 * * *", exp: "at 15 and 45 minutes past the hour, between 08:00 and 18:59"},
+		{expr: "0 0 1,15 * *", exp: "at 00:00, on days 1 and 15 of the month"},
+		{expr: "0 12 * JAN,JUL *", exp: "at 12:00, in January and July"},
+
+		// Steps
+		{expr: "10-50/10 * * * *", exp: "every 10 minutes from minute 10 through 50 past the hour"},
+		{expr: "5/15 * * * *", exp: "every 15 minutes from minute 5 through 59 past the hour"},
+		{expr: "0 9-17/2 * * *", exp: "every 2 hours from 09:00 through 17:00"},
+		{expr: "*/5 */2 * * *", exp: "every 5 minutes, every 2 hours"},
+		{expr: "0 0 */2 * *", exp: "at 00:00, every 2 days"},
+		{expr: "0 0 1 */3 *", exp: "at 00:00, on day 1 of the month, every 3 months"},
+
+		// Days of the week
+		{expr: "0 0 * * 0", exp: "at 00:00, on Sunday"},
+		{expr: "30 9 * * MON-FRI", exp: "at 09:30, on Monday through Friday"},
+		{expr: "0 9 * * 1-5", exp: "at 09:00, on Monday through Friday"},
+		{expr: "0 9 * * MON,WED,FRI", exp: "at 09:00, on Monday, Wednesday and Friday"},
+		{expr: "0 12 * JUL SAT", exp: "at 12:00, on Saturday, in July"},
+		{expr: "0 0 1 * MON", exp: "at 00:00, on day 1 of the month or on Monday"},
+
+		// Seconds, descriptors and timezones
+		{expr: "* * * * * *", exp: "every second"},
+		{expr: "*/10 * * * * *", exp: "every 10 seconds"},
+		{expr: "30 0 9 * * *", exp: "at 09:00:30"},
+		{expr: "*/5 0 9 * * *", exp: "every 5 seconds, during 09:00"},
+		{expr: "*/30 * 9 * * *", exp: "every 30 seconds, between 09:00 and 09:59"},
+		{expr: "@hourly", exp: "every hour"},
+		{expr: "@daily", exp: "at 00:00"},
+		{expr: "@weekly", exp: "at 00:00, on Sunday"},
+		{expr: "@every 90m", exp: "every 1h30m0s"},
+		{expr: "TZ=Europe/London 0 9 * * *", exp: "at 09:00, in the Europe/London timezone"},
+	} {
+		e, err := InitFunction("cron_describe", test.expr)
+		require.NoError(t, err, test.expr)
+
+		res, err := e.Exec(FunctionContext{})
+		require.NoError(t, err, test.expr)
+		assert.Equal(t, test.exp, res, test.expr)
+	}
+
+	_, err := InitFunction("cron_describe", "not a cron")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse cron expression")
+}
+
+func TestFunctionMemoize(t *testing.T) {
+	var evaluations int
+	expensive := ClosureFunction("function expensive", func(ctx FunctionContext) (interface{}, error) {
+		evaluations++
+		v := ctx.Value()
+		if v == nil {
+			return nil, errors.New("no context")
+		}
+		return fmt.Sprintf("result %v", *v), nil
+	}, nil)
+
+	siteA, err := InitFunction("memoize", expensive)
+	require.NoError(t, err)
+
+	siteB, err := InitFunction("memoize", expensive)
+	require.NoError(t, err)
+
+	ctx := FunctionContext{ExecShared: NewSharedState()}.WithValue("foo")
+	for i := 0; i < 3; i++ {
+		res, err := siteA.Exec(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "result foo", res)
+	}
+	assert.Equal(t, 1, evaluations)
+
+	res, err := siteB.Exec(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "result foo", res)
+	assert.Equal(t, 2, evaluations, "separate calls without a key should not share a result")
+
+	// A new execution has fresh state.
+	ctx = FunctionContext{ExecShared: NewSharedState()}.WithValue("foo")
+	res, err = siteA.Exec(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "result foo", res)
+	assert.Equal(t, 3, evaluations)
+
+	// Without execution state there is nowhere to store results.
+	_, err = siteA.Exec(FunctionContext{}.WithValue("baz"))
+	require.NoError(t, err)
+	_, err = siteA.Exec(FunctionContext{}.WithValue("baz"))
+	require.NoError(t, err)
+	assert.Equal(t, 5, evaluations)
+}
+
+func TestFunctionMemoizeStructuredContext(t *testing.T) {
+	var evaluations int
+	expensive := ClosureFunction("function expensive", func(ctx FunctionContext) (interface{}, error) {
+		evaluations++
+		return map[string]interface{}{"n": int64(evaluations)}, nil
+	}, nil)
+
+	memo, err := InitFunction("memoize", expensive)
+	require.NoError(t, err)
+
+	ctx := FunctionContext{ExecShared: NewSharedState()}.WithValue(map[string]interface{}{"doc": "foo"})
+	for i := 0; i < 3; i++ {
+		res, err := memo.Exec(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"n": int64(1)}, res)
+
+		// Mutating a result must not modify the cached result.
+		res.(map[string]interface{})["n"] = "mutated"
+	}
+	assert.Equal(t, 1, evaluations)
+
+	// Structured context values are compared by identity.
+	_, err = memo.Exec(ctx.WithValue(map[string]interface{}{"doc": "foo"}))
+	require.NoError(t, err)
+	assert.Equal(t, 2, evaluations)
+}
+
+func TestFunctionMemoizeKeyed(t *testing.T) {
+	var evaluations int
+	expensive := ClosureFunction("function expensive", func(ctx FunctionContext) (interface{}, error) {
+		evaluations++
+		return "result " + IToString(*ctx.Value()), nil
+	}, nil)
+
+	siteA, err := InitFunction("memoize", expensive, NewFieldFunction(""))
+	require.NoError(t, err)
+
+	siteB, err := InitFunction("memoize", expensive, NewFieldFunction(""))
+	require.NoError(t, err)
+
+	shared := NewSharedState()
+	for _, v := range []string{"foo", "bar", "foo", "bar", "baz"} {
+		for _, site := range []Function{siteA, siteB} {
+			res, err := site.Exec(FunctionContext{ExecShared: shared}.WithValue(v))
+			require.NoError(t, err)
+			assert.Equal(t, "result "+v, res)
+		}
+	}
+	assert.Equal(t, 3, evaluations, "separate calls with the same key should share a result")
+
+	// Keys of different types must not share a result.
+	for _, v := range []interface{}{int64(1), "1", json.Number("1"), []byte("1"), "1"} {
+		res, err := siteA.Exec(FunctionContext{ExecShared: shared}.WithValue(v))
+		require.NoError(t, err)
+		assert.Equal(t, "result 1", res)
+	}
+	assert.Equal(t, 6, evaluations)
+
+	_, err = siteA.Exec(FunctionContext{ExecShared: shared}.WithValue([]interface{}{"foo"}))
+	require.EqualError(t, err, "expected string, number, bool or null value, got array from field `this`")
+}
+
+func TestFunctionMemoizeMapEach(t *testing.T) {
+	var evaluations int
+	upper, err := InitMethod("uppercase", NewFieldFunction(""))
+	require.NoError(t, err)
+
+	counted := ClosureFunction("function counted", func(ctx FunctionContext) (interface{}, error) {
+		evaluations++
+		return upper.Exec(ctx)
+	}, nil)
+
+	memo, err := InitFunction("memoize", counted)
+	require.NoError(t, err)
+
+	mapFn, err := InitMethod("map_each", NewFieldFunction(""), memo)
+	require.NoError(t, err)
+
+	res, err := mapFn.Exec(FunctionContext{
+		ExecShared: NewSharedState(),
+	}.WithValue([]interface{}{"a", "b", "a", "c"}))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"A", "B", "A", "C"}, res)
+	assert.Equal(t, 3, evaluations)
+}
+
+func TestFunctionMemoizeNamedContext(t *testing.T) {
+	var evaluations int
+	counted := ClosureFunction("function counted", func(ctx FunctionContext) (interface{}, error) {
+		evaluations++
+		v, _ := ctx.NamedValue("ele")
+		return v, nil
+	}, nil)
+
+	memo, err := InitFunction("memoize", counted)
+	require.NoError(t, err)
+
+	ctx := FunctionContext{ExecShared: NewSharedState()}.WithValue("root")
+	for _, v := range []string{"a", "b", "a"} {
+		res, err := memo.Exec(ctx.WithNamedValue("ele", v))
+		require.NoError(t, err)
+		assert.Equal(t, v, res)
+	}
+	assert.Equal(t, 2, evaluations)
+}
+
+func TestFunctionMemoizeNested(t *testing.T) {
+	upper, err := InitMethod("uppercase", NewFieldFunction("doc"))
+	require.NoError(t, err)
+
+	inner, err := InitFunction("memoize", upper, NewFieldFunction("doc"))
+	require.NoError(t, err)
+
+	outer, err := InitFunction("memoize", inner)
+	require.NoError(t, err)
+
+	res, err := outer.Exec(FunctionContext{
+		ExecShared: NewSharedState(),
+	}.WithValue(map[string]interface{}{"doc": "hello"}))
+	require.NoError(t, err)
+	assert.Equal(t, "HELLO", res)
+}
+
+func TestFunctionMemoizeErrors(t *testing.T) {
+	var evaluations int
+	failing := ClosureFunction("function failing", func(ctx FunctionContext) (interface{}, error) {
+		evaluations++
+		return nil, errors.New("nope")
+	}, nil)
+
+	fn, err := InitFunction("memoize", failing)
+	require.NoError(t, err)
+
+	ctx := FunctionContext{ExecShared: NewSharedState()}
+	for i := 0; i < 2; i++ {
+		_, err = fn.Exec(ctx)
+		require.EqualError(t, err, "nope")
+	}
+	assert.Equal(t, 2, evaluations)
+}
+
+func TestFunctionBatchIndexAndSize(t *testing.T) {
+	msg := message.New([][]byte{
+		[]byte(`{"id":"first"}`),
+		[]byte(`{"id":"second"}`),
+		[]byte(`{"id":"third"}`),
+	})
+
+	indexFn, err := InitFunction("batch_index")
+	require.NoError(t, err)
+
+	sizeFn, err := InitFunction("batch_size")
+	require.NoError(t, err)
+
+	firstFn, err := InitFunction("json", "id")
+	require.NoError(t, err)
+	firstFn, err = InitMethod("from", firstFn, int64(0))
+	require.NoError(t, err)
+
+	for i := 0; i < msg.Len(); i++ {
+		ctx := FunctionContext{
+			Index:    i,
+			MsgBatch: msg,
+		}
+
+		res, err := indexFn.Exec(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(i), res)
+
+		res, err = sizeFn.Exec(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(3), res)
+
+		res, err = firstFn.Exec(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, "first", res)
+	}
+
+	res, err := sizeFn.Exec(FunctionContext{MsgBatch: message.New(nil)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), res)
+}
+
+func TestFunctionSibling(t *testing.T) {
+	msg := message.New([][]byte{
+		[]byte(`{"id":"first"}`),
+		[]byte(`{"id":"second"}`),
+		[]byte(`{"id":"third"}`),
+	})
+
+	siblingID := func(t *testing.T, index int, args ...interface{}) (interface{}, error) {
+		t.Helper()
+		fn, err := InitFunction("sibling", args...)
+		require.NoError(t, err)
+
+		res, err := fn.Exec(FunctionContext{
+			Index:    index,
+			MsgBatch: msg,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return res.(map[string]interface{})["id"], nil
+	}
+
+	res, err := siblingID(t, 1, int64(-1))
+	require.NoError(t, err)
+	assert.Equal(t, "first", res)
+
+	res, err = siblingID(t, 1, int64(1))
+	require.NoError(t, err)
+	assert.Equal(t, "third", res)
+
+	res, err = siblingID(t, 1, int64(0))
+	require.NoError(t, err)
+	assert.Equal(t, "second", res)
+
+	res, err = siblingID(t, 2, int64(0), true)
+	require.NoError(t, err)
+	assert.Equal(t, "first", res)
+
+	res, err = siblingID(t, 0, int64(2), true)
+	require.NoError(t, err)
+	assert.Equal(t, "third", res)
+
+	_, err = siblingID(t, 0, int64(-1))
+	require.EqualError(t, err, "message index -1 is out of bounds for a batch of size 3")
+
+	_, err = siblingID(t, 2, int64(1))
+	require.EqualError(t, err, "message index 3 is out of bounds for a batch of size 3")
+
+	_, err = siblingID(t, 0, int64(3), true)
+	require.EqualError(t, err, "message index 3 is out of bounds for a batch of size 3")
+}
+
+func TestFunctionSiblingNotJSON(t *testing.T) {
+	fn, err := InitFunction("sibling", int64(1))
+	require.NoError(t, err)
+
+	_, err = fn.Exec(FunctionContext{
+		MsgBatch: message.New([][]byte{[]byte(`{}`), []byte(`not json`)}),
+	})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse message 1 as JSON")
+}
+
+func TestFunctionAggregate(t *testing.T) {
+	msg := message.New([][]byte{
+		[]byte(`{"id":"first","amount":10}`),
+		[]byte(`{"id":"second","amount":2.5}`),
+		[]byte(`{"id":"third","amount":-4}`),
+	})
+	msg.Get(1).Metadata().Set("region", "eu")
+
+	tests := map[string]struct {
+		query   Function
+		reducer string
+		output  interface{}
+		err     string
+	}{
+		"sum": {
+			query:   NewFieldFunction("amount"),
+			reducer: "sum",
+			output:  8.5,
+		},
+		"max": {
+			query:   NewFieldFunction("amount"),
+			reducer: "max",
+			output:  float64(10),
+		},
+		"min": {
+			query:   NewFieldFunction("amount"),
+			reducer: "min",
+			output:  float64(-4),
+		},
+		"count": {
+			query:   NewFieldFunction("amount"),
+			reducer: "count",
+			output:  int64(3),
+		},
+		"collect": {
+			query:   NewFieldFunction("id"),
+			reducer: "collect",
+			output:  []interface{}{"first", "second", "third"},
+		},
+		"collect ignores nulls": {
+			query:   NewFieldFunction("nope"),
+			reducer: "collect",
+			output:  []interface{}{},
+		},
+		"sum of nothing": {
+			query:   NewFieldFunction("nope"),
+			reducer: "sum",
+			output:  float64(0),
+		},
+		"max of nothing": {
+			query:   NewFieldFunction("nope"),
+			reducer: "max",
+			output:  nil,
+		},
+		"message functions": {
+			query: func() Function {
+				fn, err := InitFunction("meta", "region")
+				require.NoError(t, err)
+				fn, err = InitMethod("catch", fn, nil)
+				require.NoError(t, err)
+				return fn
+			}(),
+			reducer: "collect",
+			output:  []interface{}{"eu"},
+		},
+		"sum of strings": {
+			query:   NewFieldFunction("id"),
+			reducer: "sum",
+			err:     "result 0: expected number value, got string (\"first\")",
+		},
+	}
+
+	for name, test := range tests {
+		test := test
+		t.Run(name, func(t *testing.T) {
+			fn, err := InitFunction("aggregate", test.query, test.reducer)
+			require.NoError(t, err)
+
+			res, err := fn.Exec(FunctionContext{
+				Index:    1,
+				MsgBatch: msg,
+			}.WithValue("not the message"))
+			if test.err != "" {
+				require.EqualError(t, err, test.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.output, res)
+		})
+	}
+
+	_, err := InitFunction("aggregate", NewFieldFunction("amount"), "average")
+	require.EqualError(t, err, "unrecognised reducer: average")
+}
+
+func TestFunctionMetaKeys(t *testing.T) {
+	msg := message.New([][]byte{[]byte(`first`), []byte(`second`)})
+	msg.Get(0).Metadata().Set("kafka_topic", "foo")
+	msg.Get(0).Metadata().Set("kafka_key", "bar")
+	msg.Get(0).Metadata().Set("amqp_exchange", "baz")
+	msg.Get(0).Metadata().Set("empty", "")
+
+	fn, err := InitFunction("meta_keys")
+	require.NoError(t, err)
+
+	res, err := fn.Exec(FunctionContext{MsgBatch: msg})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"amqp_exchange", "kafka_key", "kafka_topic"}, res)
+
+	res, err = fn.Exec(FunctionContext{Index: 1, MsgBatch: msg})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{}, res)
+
+	fromFn, err := InitMethod("from", fn, int64(0))
+	require.NoError(t, err)
+
+	res, err = fromFn.Exec(FunctionContext{Index: 1, MsgBatch: msg})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"amqp_exchange", "kafka_key", "kafka_topic"}, res)
+
+	_, targets := fn.QueryTargets(TargetsContext{})
+	assert.Equal(t, []TargetPath{NewTargetPath(TargetMetadata)}, targets)
+}
+
+func TestFunctionErrorTargets(t *testing.T) {
+	msg := message.New([][]byte{[]byte(`first`), []byte(`second`)})
+	msg.Get(1).Metadata().Set(types.FailFlagKey, "it broke")
+
+	errFn, err := InitFunction("error")
+	require.NoError(t, err)
+
+	erroredFn, err := InitFunction("errored")
+	require.NoError(t, err)
+
+	res, err := errFn.Exec(FunctionContext{MsgBatch: msg})
+	require.NoError(t, err)
+	assert.Equal(t, "", res)
+
+	res, err = erroredFn.Exec(FunctionContext{MsgBatch: msg})
+	require.NoError(t, err)
+	assert.Equal(t, false, res)
+
+	res, err = errFn.Exec(FunctionContext{Index: 1, MsgBatch: msg})
+	require.NoError(t, err)
+	assert.Equal(t, "it broke", res)
+
+	res, err = erroredFn.Exec(FunctionContext{Index: 1, MsgBatch: msg})
+	require.NoError(t, err)
+	assert.Equal(t, true, res)
+
+	for _, fn := range []Function{errFn, erroredFn} {
+		_, targets := fn.QueryTargets(TargetsContext{})
+		assert.Equal(t, []TargetPath{NewTargetPath(TargetMetadata, types.FailFlagKey)}, targets, fn.Annotation())
+	}
+}
+
+func TestFunctionContentLengthRaw(t *testing.T) {
+	msg := message.New([][]byte{[]byte(`{ "foo" :  [ 1, 2 ] }`)})
+
+	// Parse the message first, the length must remain that of the raw bytes.
+	_, err := msg.Get(0).JSON()
+	require.NoError(t, err)
+
+	fn, err := InitFunction("content_length")
+	require.NoError(t, err)
+
+	res, err := fn.Exec(FunctionContext{MsgBatch: msg})
+	require.NoError(t, err)
+	assert.Equal(t, int64(21), res)
+}
+
+func TestFunctionBatchSlice(t *testing.T) {
+	msg := message.New([][]byte{
+		[]byte(`{"id":0}`),
+		[]byte(`{"id":1}`),
+		[]byte(`{"id":2}`),
+		[]byte(`{"id":3}`),
+		[]byte(`{"id":4}`),
+	})
+
+	tests := []struct {
+		args []interface{}
+		ids  []interface{}
+	}{
+		{args: []interface{}{int64(0)}, ids: []interface{}{0, 1, 2, 3, 4}},
+		{args: []interface{}{int64(1), int64(3)}, ids: []interface{}{1, 2}},
+		{args: []interface{}{int64(3), int64(4)}, ids: []interface{}{3}},
+		{args: []interface{}{int64(-2)}, ids: []interface{}{3, 4}},
+		{args: []interface{}{int64(0), int64(-2)}, ids: []interface{}{0, 1, 2}},
+		{args: []interface{}{int64(-3), int64(-1)}, ids: []interface{}{2, 3}},
+		{args: []interface{}{int64(-10), int64(2)}, ids: []interface{}{0, 1}},
+		{args: []interface{}{int64(3), int64(100)}, ids: []interface{}{3, 4}},
+		{args: []interface{}{int64(10)}, ids: []interface{}{}},
+		{args: []interface{}{int64(3), int64(1)}, ids: []interface{}{}},
+		{args: []interface{}{int64(0), int64(-10)}, ids: []interface{}{}},
+	}
+
+	for _, test := range tests {
+		fn, err := InitFunction("batch_slice", test.args...)
+		require.NoError(t, err)
+
+		res, err := fn.Exec(FunctionContext{MsgBatch: msg})
+		require.NoError(t, err)
+
+		ids := []interface{}{}
+		for _, doc := range res.([]interface{}) {
+			id, err := IGetInt(doc.(map[string]interface{})["id"])
+			require.NoError(t, err)
+			ids = append(ids, int(id))
+		}
+		assert.Equal(t, test.ids, ids, fmt.Sprintf("%v", test.args))
+	}
+
+	fn, err := InitFunction("batch_slice", int64(0))
+	require.NoError(t, err)
+
+	_, err = fn.Exec(FunctionContext{MsgBatch: message.New([][]byte{[]byte(`{}`), []byte(`nope`)})})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to parse message 1 as JSON")
+}
+
+func TestFileLinesFunction(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := map[string]struct {
+		content string
+		output  []interface{}
+	}{
+		"lf endings": {
+			content: "foo\nbar\nbaz\n",
+			output:  []interface{}{"foo", "bar", "baz"},
+		},
+		"crlf endings": {
+			content: "foo\r\nbar\r\nbaz\r\n",
+			output:  []interface{}{"foo", "bar", "baz"},
+		},
+		"mixed endings without trailing": {
+			content: "foo\r\nbar\nbaz",
+			output:  []interface{}{"foo", "bar", "baz"},
+		},
+		"inner empty lines": {
+			content: "foo\n\nbar\n\n",
+			output:  []interface{}{"foo", "", "bar", ""},
+		},
+		"empty file": {
+			content: "",
+			output:  []interface{}{},
+		},
+	}
+
+	for name, test := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(name, " ", "_"))
+		require.NoError(t, ioutil.WriteFile(path, []byte(test.content), 0o644))
+
+		e, err := InitFunction("file_lines", path)
+		require.NoError(t, err, name)
+
+		res, err := e.Exec(FunctionContext{})
+		require.NoError(t, err, name)
+		assert.Equal(t, test.output, res, name)
+	}
+
+	_, err := InitFunction("file_lines", filepath.Join(dir, "does_not_exist"))
+	require.Error(t, err)
+}
+
+func TestFileJSONFunction(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "reference.json")
+
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeFile := func(content string, modTime time.Time) {
+		t.Helper()
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o644))
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+	writeFile(`{"foo":{"bar":[1,"two"]}}`, modTime)
+
+	e, err := InitFunction("file_json", path)
+	require.NoError(t, err)
+
+	res, err := e.Exec(FunctionContext{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"foo": map[string]interface{}{"bar": []interface{}{float64(1), "two"}},
+	}, res)
+
+	// Mutating the result must not affect the cache.
+	res.(map[string]interface{})["foo"] = "mutated"
+
+	// Changing the contents without changing the modification time returns
+	// the cached document.
+	writeFile(`{"foo":"changed"}`, modTime)
+
+	res, err = e.Exec(FunctionContext{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"foo": map[string]interface{}{"bar": []interface{}{float64(1), "two"}},
+	}, res)
+
+	// A new modification time invalidates the cache.
+	writeFile(`{"foo":"changed"}`, modTime.Add(time.Minute))
+
+	res, err = e.Exec(FunctionContext{})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"foo": "changed"}, res)
+
+	writeFile(`{"foo":`, modTime.Add(2*time.Minute))
+
+	_, err = e.Exec(FunctionContext{})
+	require.EqualError(t, err, fmt.Sprintf("failed to parse file %v as JSON: unexpected end of JSON input", path))
+
+	e, err = InitFunction("file_json", filepath.Join(dir, "does_not_exist.json"))
+	require.NoError(t, err)
+
+	_, err = e.Exec(FunctionContext{})
+	require.Error(t, err)
+}
+
+func TestGlobFunction(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "nested"), 0o755))
+	for _, name := range []string{"b.json", "a.json", "c.json", "d.txt", "json", "nested/e.json"} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("{}"), 0o644))
+	}
+
+	tests := map[string]struct {
+		pattern string
+		output  []interface{}
+	}{
+		"json files": {
+			pattern: filepath.Join(dir, "*.json"),
+			output: []interface{}{
+				filepath.Join(dir, "a.json"),
+				filepath.Join(dir, "b.json"),
+				filepath.Join(dir, "c.json"),
+			},
+		},
+		"character class": {
+			pattern: filepath.Join(dir, "[bd].*"),
+			output: []interface{}{
+				filepath.Join(dir, "b.json"),
+				filepath.Join(dir, "d.txt"),
+			},
+		},
+		"nested directories": {
+			pattern: filepath.Join(dir, "*", "*.json"),
+			output: []interface{}{
+				filepath.Join(dir, "nested", "e.json"),
+			},
+		},
+		"no matches": {
+			pattern: filepath.Join(dir, "*.yaml"),
+			output:  []interface{}{},
+		},
+	}
+
+	for name, test := range tests {
+		e, err := InitFunction("glob", test.pattern)
+		require.NoError(t, err, name)
+
+		res, err := e.Exec(FunctionContext{})
+		require.NoError(t, err, name)
+		assert.Equal(t, test.output, res, name)
+	}
+
+	_, err := InitFunction("glob", "[")
+	require.EqualError(t, err, "invalid glob pattern [: syntax error in pattern")
+}
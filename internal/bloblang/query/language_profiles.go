@@ -0,0 +1,163 @@
+package query
+
+// languageTrigrams contains a profile for each language supported by the
+// method detect_language, consisting of the 300 most common character trigrams
+// of the language in descending order of frequency, separated by pipes. The
+// trigrams are taken from lowercase words padded with a single space on either
+// side, such that " th" is a trigram at the start of a word.
+var languageTrigrams = map[string]string{
+	// German
+	"de": "" +
+		"en |er |ie |ten| di|die| de| un|der|sch|gen|nd |und|eit| da|" +
+		"ste|ich| be| si| zu|das| ge|as |sen| wa| wi|ch |hre|um | ei|" +
+		"den|ere|nge|ung| ha| me| st| vo|ein|ens|es |nde|re |sse|te |" +
+		"ver| er| le| sc| ve| we|ber|cht|in |ng |ter|war| al| um|che|" +
+		"hen|ir |it |ite|nen|on |sie|zu |bes|eis|el |ers|ess|ier|ine|" +
+		"ler|ner|ren|sta|wir| in| se| so|ass|bei|ern|ert|ft |hat|iel|" +
+		"ist|lle|men|ne |ns |rei|rge|ss |zei| au| es| mi| sa| sp| ta|" +
+		"an |ang|auf|ben|ede|end|est|hte|ien|le |lt |mei|mit|nsc|nte|" +
+		"rer|rte|ser|sic|tra|tte|uf |uns|vie|von|wei| br| en| fa| gr|" +
+		" ih| na| ze| üb|abe|ach|ag |ahr|als|ar |are|at |att|aus|chl|" +
+		"cke|de |ehr|eim|ent|erg|fen|ge |ges|haf|her|hne|ht |ige|ihr|" +
+		"lan|les|lic|man|oft|rat|rau|rbe|rsc|rt |sei|st |tag|tau|uch|" +
+		"vor|was|wic|woh|übe| an| ar| bi| he| ja| je| ma| ne| of| pr|" +
+		" re| te| vi| wo|aft|all|am |ara|arb|cha|chi|chr|chu|ckl|dar|" +
+		"dem|des|eie|eil|ell|elt|em |ene|erd|erl|eru|ese|et |eue|ger|" +
+		"gro|gte|hab|ick|ig |im |imm|ion|its|jah|jed|kei|kli|kt |lie|" +
+		"ls |lte|nac|neu|nne|nse|ntw|ohn|pie|rch|rde|res|rin|rme|rn |" +
+		"rne|roß|rst|run|rän|sig|so |son|spi|suc|tad|tel|tet|tig|tun|" +
+		"twi|use|uss|wer|ßen|ück| ab| am| bü| dr| du| fl| fr| fä| hi|" +
+		" im| ka| ki| kl| mö| ni| wu| zw|adt|agt|al |alt|ast|ate|aub",
+	// English
+	"en": "" +
+		" th|the|he |nd |ng |and|ing|er | an| of|re | to|of |to | we|" +
+		"ed |rs | wa|at | fo| re|ere|ers|for|hat|st | in|in |tha| ha|" +
+		" st| wi|her|ld |le |or |ter| a | be| co| wh|ad |ear|es |me |" +
+		"rea|ver| al| se| te| wo|as |ch |eve|ey |hey|hou|ner|th |ts |" +
+		"was|we | ev| mo|en |ith|on |our|res|se |tho|wer|wit| ch| cl|" +
+		" de| en| ho| li| ma| ne| on| pr| sh|ay |ble|ds |ead|ent|ery|" +
+		"est|ll |new|ong|ore|ost|oul|oun|ous|ry |uld| br| fa| gr| he|" +
+		" is| me| pe| ti|any|are|ce |cou|day|em |ew |fte|had|hin|ide|" +
+		"ill|ime|is |lea|ls |ly |mos|nge|nin|ns |nt |od |ome|ose|oug|" +
+		"per|pro|rou|sin|sto|str|te |tea|ten|thi|tim|ugh|ut |wor| ab|" +
+		" bu| by| cu| fi| la| le| lo| ou| sc| si| so| su| tr| us| ye|" +
+		"ach|age|ain|al |all|ars|ast|ate|aye|bou|by |cha|ct |cus|den|" +
+		"eac|eat|enc|ene|eop|era|ge |ger|ges|gh |gin|han|hem|hil|ice|" +
+		"ies|il |ild|int|ion|lay|lon|mer|mor|nce|nds|ngi|oft|ood|ook|" +
+		"opl|out|peo|ple|rai|rat|ree|rie|ron|san|sea|sta|stu|tte|tud|" +
+		"ty |und|ur |us |usa|use|war|whe|win|wou|yea| af| da| di| ex|" +
+		" ge| jo| kn| pl| ra| ri| ro| sa| sk| un| vi| yo|abl|abo|adi|" +
+		"aft|aid|alm|als|am |ame|an |ang|ans|ant|app|arm|arn|atc|ati|" +
+		"aus|ave|bec|bef|ber|bet|bre|bri|but|cau|che|chi|cil|cle|clo|" +
+		"cor|dep|der|dis|eam|eas|eca|eer|efo|ell|els|emb|end|eni|eri",
+	// Spanish
+	"es": "" +
+		"os | de| la|de |es |as |la |el | lo|que| qu|ue | co| ca| el|" +
+		"ra | y |en |los|do |ent|par|on | pa|con|nte| en| pr|les| a |" +
+		"ara|ien| es| ha| un|ar |era|ida|por|ás | má| re|ant|da |est|" +
+		"más|nta|or |te |ía | al| po|aba|ero|ier|las|pre|res|to | me|" +
+		" se| su| te|ado|al |an |dad|emp|lo |mos|nos|per|ron|sta| le|" +
+		" ma| mu| vi|dos|end|men|ndo|pro|ros|tes|tra| mi| no| nu| pe|" +
+		"arr|art|ba |ble|cio|des|er |gen|ido|io |ios|ir |lar|na |nde|" +
+		"nto|qui|re |ren|rio|se |uer|un |ura| cl| ge| in| ll| ni| pu|" +
+		" so| ta| tr| ve|aci|ad |ada|ami|amo|and|arg|cal|cam|com|cos|" +
+		"del|eci|ene|eña|hab|ima|lle|ma |mil|mpo|nar|nes|no |nue|ona|" +
+		"po |pue|rad|ran|rec|rri|sa |ta |tam|ten|uel|uev|vid|ños| an|" +
+		" añ| ba| cu| du| er| fu| ju| si| to|abl|ale|amb|ana|ari|aro|" +
+		"ayu|año|ca |car|cho|cli|cua|die|ejo|enc|ení|equ|eri|esa|esp|" +
+		"evo|fue|idi|iem|ile|ili|ion|ión|jar|jor|len|lid|mar|mbi|mej|" +
+		"mie|mpr|muc|ner|nie|ntr|nía|odo|ons|ont|ore|osa|otr|pid|ras|" +
+		"rno|rte|rti|tab|tan|tar|tem|ter|tor|tre|tro|uch|udi|una|und|" +
+		"uni|unt|vos|íam|ón | ab| ap| au| ay| ce| ci| di| do| dí| em|" +
+		" eq| ex| hi| li| pi| ro| ti| va| úl|abi|abí|act|ade|aja|apr|" +
+		"ard|asi|ast|ayo|aña|ban|bar|bas|bil|bio|bre|bía|cad|can|cas|" +
+		"ce |ció|cto|ctu|cue|cía|das|dec|den|der|dia|don|dur|día|ect",
+	// French
+	"fr": "" +
+		"es | de|nt | le|ent|de |les|le | qu|des|us | la|et |la |que|" +
+		"re |ue | et| pr|er |our|ur | co|ant|ns | no| po| en| ma| pl|" +
+		"ien|is |it |ons|plu|rs | ét|ion|lus|nou|res|ill|lle|men|ont|" +
+		"ous|pou|ts | ch|ait|cha|che|pre|pro|qui|tai| au| av| se| un|" +
+		"con|ers|ort|ouv|ter|ux | ce| re| so| tr| à |aie|ais|eme|eur|" +
+		"ier|ire|nts|par|tio|uve|éta| a | lo| to|ati|ava|ce |emp|en |" +
+		"end|ois|on |por|rai|ren|te |tou|ui |vai| ap| du| dé| fo| l |" +
+		" me| mi| pa| pe| vi|art|au |com|du |eil|ell|eux|in |jou|lie|" +
+		"nde|nes|nte|out|rt |rte|se |tem|tre|un |ure|ven|ère| an| be|" +
+		" cl| d | il| jo| mo| on| ré| su| te|air|and|ans|ave|ces|cou|" +
+		"dan|eau|ens|ess|est|her|ign|il |ièr|leu|mai|mat|mil|mon|nne|" +
+		"nse|ond|ong|onn|pér|rop|sen|son|sou|sse|ten|tes|tra|uit|une|" +
+		"urs|ut | ca| da| el| es| fa| li| sa| vo|age|ain|app|aqu|ard|" +
+		"aux|bre|cie|cli|der|dev|dé |ec |el |enc|ern|foi|gen|gne|gén|" +
+		"han|haq|he |ie |ieu|ils|ine|isa|ise|ive|lla|lli|lon|ls |mar|" +
+		"me |mpé|nce|nd |ne |nen|ner|nta|ntr|omp|pen|pos|pré|qua|rat|" +
+		"rav|rch|rem|reu|riv|rou|rri|rès|sai|sei|ser|squ|ssi|st |sur|" +
+		"tro|tte|tur|té |ues|van|vec|ver|vie|ès |ées|éri|és | ar| c |" +
+		" di| ex| fi| ge| gé| hi| in| pé| ra| ro| ru| s | si| éc| éq|" +
+		"act|ann|apr|arc|arr|ass|at |atc|auc|aus|bea|bel|ble|ch |cip",
+	// Italian
+	"it": "" +
+		" co|no |re |to | di|la |ti | ch| il|di |il | e | la|ent|he |" +
+		" de|che|con|gli|per|te | pe|li | ca|ra | in| pi| st|del| a |" +
+		" qu|ell|er |era|ta | pr|ano|do |ere|nti|qua| ri| se|are|el |" +
+		"gio|iù |men|ni |nte|più|si |va | i | mi| no| un|ant|da |eri|" +
+		"ett|ia |igl|le |mo |nta|ono| er| le| ma| po| so| te|art|ato|" +
+		"cos|eva|ien|ior|ma |ne |on |ost|par|pro|sta|str| al| an| gl|" +
+		" ha| mo| pa|ame|amo|ann|ati|emp|ess|ggi|iam|ima|io |ion|ita|" +
+		"lla|mig|na |ndo|olt|po |ran|ri |sa |tan|tar|tem|tra|un |ve |" +
+		" do| su| vi|amb|and|bil|cci|chi|egn|end|ene|gna|lo |mbi|nda|" +
+		"ner|ond|ont|ort|rie|ro |rte|rti|ser|spe|ssi|sto|tto|tà | av|" +
+		" fa| fi| ge| gi| ne| si| sp| tr| vo| è |acc|asi|att|bia|cam|" +
+		"cer|cit|com|de |erc|gen|gia|ier|ili|in |ina|ità|lie|lio|me |" +
+		"mpo|nal|nat|one|ora|ove|pes|rar|res|ris|se |so |tat|ter|tre|" +
+		"tti|ual|uov| ba| ci| cl| da| fe| im| li| lo| lu| nu| og| sc|" +
+		"abi|ade|agg|ai |aia|all|ara|ava|ave|azi|cia|cli|co |egg|enz|" +
+		"erv|est|gge|gni|ha |han|iai|ian|iar|ibi|ice|ie |imp|ino|ins|" +
+		"isc|ito|ive|leg|lia|lit|lli|lta|lti|lun|mol|mon|mpe|nde|nel|" +
+		"ngo|nni|nno|non|nos|nsi|nuo|odo|ogn|oi |ome|ons|opo|ore|ori|" +
+		"orn|oro|osa|por|pos|pre|pri|ren|riv|rri|sci|seg|sia|sig|sol|" +
+		"son|sso|ste|stu|tim|tta|tud|uas|ult|una|ung|ura|vat|vev|vol",
+	// Dutch
+	"nl": "" +
+		"en |de | de| he|et |gen|ten| wa|den|er | en|at |ste|te | ve|" +
+		" we|een|ver|aar|ere|het|der| be| te|ren| va|est|nde| da| ee|" +
+		" om|an |ers| me| ze|are|eer|om |ar |dat|ing|oor|ter| ge| in|" +
+		" le| on|in |nge|sch|we | op| st| vo|ede|ens|lan|lle|men|rde|" +
+		"rij|van| bi| ha| la| wi|ang|bij|el |ete|ng |op |rs |war|ze |" +
+		" al| di| zo|aat|ees|end|era|ert|ie |ige|ijd|ken|len|nen|ns |" +
+		"nte|ond|re |zen| er| na| sc|aan|as |bes|dag|die|eld|erd|ge |" +
+		"ij |mee|moe|nie|oek|or |ord|per|ran|rge|sta|tij|uwe|vaa|ven|" +
+		"voo|waa|was|wij| aa| bo| do| du| el| ho| mo| ni| pr| re| wo|" +
+		" zi|ag |am |ant|cht|dig|eel|eke|elk|erg|erk|es |euw|gel|ier|" +
+		"ijn|jd |ke |laa|ler|met|ner|oen|ons|ont|rei|res|st |vee|wat|" +
+		"wee|wer|zoe| br| bu| ja| je| ke| ko| ma| se| to|aak|ach|ad |" +
+		"age|ak |all|and|ard|ber|bet|chr|doo|dui|ege|ein|ele|ent|erh|" +
+		"eri|eve|ges|haa|had|hel|hoe|hri|ied|ieu|ijk|ikk|ize|je |kel|" +
+		"kke|kt |lde|lee|lev|lke|maa|na |nd |nne|nse|ntw|one|ouw|ove|" +
+		"rat|rin|roo|rst|rt |sen|str|tel|twe|uiz|vie|won|zin| ba| ei|" +
+		" ga| hu| is| ka| ki| kl| lo| mi| ov| pl| ri| sn| ti| tw| zu|" +
+		"aad|add|al |ale|ame|arm|ast|ate|ats|ban|bbe|bel|ben|cha|cho|" +
+		"dde|dri|dst|eam|ebb|eds|ei |eid|ek |ela|eme|ene|eno|erv|erz|" +
+		"esl|esp|ess|eze|ft |gem|ger|gev|ghe|gri|hap|heb|hed|hee|hoo",
+	// Portuguese
+	"pt": "" +
+		"os |as | de|es |que| co| qu|de |ra |da |res|ue | e | o | a |" +
+		" ma|am |ara|is |ar |com|do |nte| da| es| se|ais|est|par|te |" +
+		" ca| do| os| pa| pr|ent|mai| mu| pe|em |nos|pre|ram|to | as|" +
+		" no|ant|ia |nha|om |por| po|con|emp|era|ida|ma |sa |se | te|" +
+		" um| ve|dos|er |ess|har|mos|nta|pro|qua|ria|sta|tem| at| re|" +
+		"ade|das|go |ias|ira|lha|mui|na |or |tra|uit| an| ao| di| en|" +
+		" fo| me| na|ada|ado|are|ava|hor|ien|ilh|inh|mpo|mpr|nde|ont|" +
+		"ora|ore|ort|pes|ssa|sso|tar|tes|uda|uma|ura|ver|vez|ão | al|" +
+		" ap| cl| em| fe| in| jo| le| lo| mi| su| ta| to| vi| à |ano|" +
+		"anç|ao |cid|dad|dep|des|eir|end|equ|ere|for|io |ita|la |lon|" +
+		"mar|mpe|nça|odo|ong|per|qui|rar|ros|ta |tas|tod|uas|va |zes|" +
+		" aq| ci| er| fi| ge| há| li| so| tr|amo|anh|aqu|até|cam|cia|" +
+		"cli|co |dar|dei|dir|ece|eci|edi|el |elh|erv|esc|eve|eze|ha |" +
+		"ham|há |ima|ipa|ir |ist|ito|jog|lho|lo |mel|men|mil|nas|nci|" +
+		"ndo|ner|ngo|nov|nti|nto|oas|ogo|omp|ons|ost|ovo|pen|po |rad|" +
+		"re |rio|ro |rte|sar|sco|ser|soa|stu|stá|tam|tan|tud|té |uan|" +
+		"um |und|uni|vam|vel|vos|ças|ênc|íve| av| ba| ch| cr| câ| du|" +
+		" eq| ex| fr| ho| lá| mo| ob| ou| ro| ti| tu| é | úl|abi|aio|" +
+		"air|al |amb|and|ape|apr|ard|ari|art|asa|ase|atr|atu|açã|açõ|" +
+		"bai|bri|can|cas|cha|che|cie|cim|cri|câm|dan|dem|der|dia|dis",
+}
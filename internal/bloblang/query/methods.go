@@ -3,9 +3,12 @@ package query
 import (
 	"errors"
 	"fmt"
+	"math"
 	"strconv"
+	"sync"
 
 	"github.com/Jeffail/gabs/v2"
+	"github.com/OneOfOne/xxhash"
 )
 
 var _ = registerMethod(
@@ -236,7 +239,7 @@ func fromAllMethod(target Function, _ ...interface{}) (Function, error) {
 var _ = registerMethod(
 	NewMethodSpec(
 		"get",
-		"Extract a field value, identified via a [dot path][field_paths], from an object.",
+		"Extract a field value, identified via a [dot path][field_paths], from an object. The path can be the result of a query, in which case it is evaluated for each invocation, and numeric path segments index into arrays. If the path does not exist then `null` is returned.",
 	).InCategory(
 		MethodCategoryObjectAndArray, "",
 		NewExampleSpec("",
@@ -246,6 +249,13 @@ var _ = registerMethod(
 			`{"foo":{"bar":"from bar","baz":"from baz"},"target":"baz"}`,
 			`{"result":"from baz"}`,
 		),
+		NewExampleSpec("",
+			`root.result = this.get(this.target)`,
+			`{"items":[{"name":"first"},{"name":"second"}],"target":"items.1.name"}`,
+			`{"result":"second"}`,
+			`{"items":[{"name":"first"},{"name":"second"}],"target":"items.2.name"}`,
+			`{"result":null}`,
+		),
 	),
 	true, getMethodCtor,
 	ExpectNArgs(1),
@@ -309,6 +319,78 @@ func getMethodCtor(target Function, args ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"set", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns a copy of a value with a field, identified via a [dot path][field_paths], set to a new value. The path can be the result of a query, in which case it is evaluated for each invocation. Intermediate objects are created when they do not exist. A numeric path segment indexes into an existing array, where an index equal to the length of the array appends a new element and an index beyond that results in an error. An empty path replaces the whole value.",
+		NewExampleSpec("",
+			`root = this.doc.set(this.path, this.value)`,
+			`{"doc":{"a":{"b":"c"}},"path":"a.d.e","value":"f"}`,
+			`{"a":{"b":"c","d":{"e":"f"}}}`,
+			`{"doc":{"a":["b","c"]},"path":"a.1","value":"d"}`,
+			`{"a":["b","d"]}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		path := gabs.DotPathToSlice(args[0].(string))
+		if args[0].(string) == "" {
+			path = nil
+		}
+		value := args[1]
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			return setAtPath(IClone(v), path, IClone(value))
+		}, nil
+	},
+	true,
+	ExpectNArgs(2),
+	ExpectStringArg(0),
+)
+
+func setAtPath(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	key, tail := path[0], path[1:]
+	switch t := root.(type) {
+	case map[string]interface{}:
+		child, err := setAtPath(t[key], tail, value)
+		if err != nil {
+			return nil, err
+		}
+		t[key] = child
+		return t, nil
+	case []interface{}:
+		i, err := strconv.Atoi(key)
+		if err != nil || i < 0 || i > len(t) {
+			return nil, fmt.Errorf("failed to set path segment '%v': index out of bounds for array of length %v", key, len(t))
+		}
+		var existing interface{}
+		if i < len(t) {
+			existing = t[i]
+		}
+		child, err := setAtPath(existing, tail, value)
+		if err != nil {
+			return nil, err
+		}
+		if i == len(t) {
+			return append(t, child), nil
+		}
+		t[i] = child
+		return t, nil
+	case nil:
+		child, err := setAtPath(nil, tail, value)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{key: child}, nil
+	}
+	return nil, fmt.Errorf("failed to set path segment '%v': %w", key, NewTypeError(root, ValueObject, ValueArray))
+}
+
+//------------------------------------------------------------------------------
+
 var _ = registerMethod(
 	NewHiddenMethodSpec("map"), false, mapMethod,
 	ExpectNArgs(1),
@@ -500,3 +582,252 @@ root.foo_type = this.foo.type()`,
 	false,
 	ExpectNArgs(0),
 )
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"enum_map", "",
+	).InCategory(
+		MethodCategoryCoercion,
+		"Translates a value into another by looking it up within an object argument. The value is converted into a string before the lookup, and therefore both numbers and strings can be used as keys. An optional second argument can be provided, which is returned when the value is not found within the object, otherwise an error is returned.",
+		NewExampleSpec("",
+			`root.status = this.code.enum_map({"1":"active","2":"closed"}, "unknown")`,
+			`{"code":1}`,
+			`{"status":"active"}`,
+			`{"code":"2"}`,
+			`{"status":"closed"}`,
+			`{"code":3}`,
+			`{"status":"unknown"}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		lookup, ok := args[0].(map[string]interface{})
+		if !ok {
+			return nil, NewTypeError(args[0], ValueObject)
+		}
+		hasDefault := len(args) > 1
+		var defaultValue interface{}
+		if hasDefault {
+			defaultValue = args[1]
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			key := IToString(ISanitize(v))
+			if res, exists := lookup[key]; exists {
+				return res, nil
+			}
+			if hasDefault {
+				return defaultValue, nil
+			}
+			return nil, fmt.Errorf("value %v was not found in the enum map", key)
+		}, nil
+	},
+	true,
+	ExpectBetweenNAndMArgs(1, 2),
+)
+
+//------------------------------------------------------------------------------
+
+const (
+	bloomDefaultCapacity = 10000
+	bloomDefaultFPRate   = 0.01
+)
+
+type bloomFilter struct {
+	capacity int64
+	fpRate   float64
+
+	m    uint64
+	k    uint64
+	bits []uint64
+	mut  sync.RWMutex
+}
+
+func newBloomFilter(capacity int64, fpRate float64) *bloomFilter {
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Round(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloomFilter{
+		capacity: capacity,
+		fpRate:   fpRate,
+		m:        m,
+		k:        k,
+		bits:     make([]uint64, (m+63)/64),
+	}
+}
+
+func (b *bloomFilter) locations(key []byte) []uint64 {
+	h1 := xxhash.Checksum64(key)
+	h2 := xxhash.Checksum64S(key, h1) | 1
+	locs := make([]uint64, b.k)
+	for i := uint64(0); i < b.k; i++ {
+		locs[i] = (h1 + i*h2) % b.m
+	}
+	return locs
+}
+
+func (b *bloomFilter) test(key []byte) bool {
+	locs := b.locations(key)
+	b.mut.RLock()
+	defer b.mut.RUnlock()
+	for _, l := range locs {
+		if b.bits[l/64]&(1<<(l%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// add sets the bits for a key and returns whether they were all already set,
+// meaning the key was probably added before.
+func (b *bloomFilter) add(key []byte) bool {
+	locs := b.locations(key)
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	present := true
+	for _, l := range locs {
+		if b.bits[l/64]&(1<<(l%64)) == 0 {
+			present = false
+			b.bits[l/64] |= 1 << (l % 64)
+		}
+	}
+	return present
+}
+
+// bloomFilterArgs are the resolved arguments of bloom_add and bloom_test.
+type bloomFilterArgs struct {
+	name     string
+	capacity int64
+	fpRate   float64
+	sized    bool
+}
+
+func parseBloomFilterArgs(args ...interface{}) (bloomFilterArgs, error) {
+	b := bloomFilterArgs{
+		name:     args[0].(string),
+		capacity: bloomDefaultCapacity,
+		fpRate:   bloomDefaultFPRate,
+	}
+	if len(args) > 1 {
+		b.sized = true
+		if b.capacity = args[1].(int64); b.capacity < 1 {
+			return b, fmt.Errorf("bloom filter capacity must be greater than zero, got %v", b.capacity)
+		}
+	}
+	if len(args) > 2 {
+		var err error
+		if b.fpRate, err = IGetNumber(args[2]); err != nil {
+			return b, err
+		}
+		if b.fpRate <= 0 || b.fpRate >= 1 {
+			return b, fmt.Errorf("bloom filter false positive rate must be between zero and one, got %v", b.fpRate)
+		}
+	}
+	return b, nil
+}
+
+// getBloomFilter returns the named bloom filter of the mapping being executed,
+// creating it if it does not yet exist.
+func getBloomFilter(ctx FunctionContext, local *SharedState, args bloomFilterArgs) (*bloomFilter, error) {
+	shared := ctx.Shared
+	if shared == nil {
+		// Queries executed outside of a mapping fall back to filters owned by
+		// the method itself.
+		shared = local
+	}
+	v, err := shared.GetOrCreate("bloom_filter "+args.name, func() (interface{}, error) {
+		return newBloomFilter(args.capacity, args.fpRate), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	b := v.(*bloomFilter)
+	if args.sized && (b.capacity != args.capacity || b.fpRate != args.fpRate) {
+		return nil, fmt.Errorf("bloom filter %v already exists with capacity %v and false positive rate %v", args.name, b.capacity, b.fpRate)
+	}
+	return b, nil
+}
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"bloom_add", "",
+	).InCategory(
+		MethodCategoryDeduplication,
+		"Adds a value to a named bloom filter and returns a boolean indicating whether the value was probably already present. Values are converted into bytes before being hashed. False positives are possible, false negatives are not.\n\nBloom filters belong to the mapping that uses them and persist for as long as the mapping does, such that a filter is shared by all messages processed by the mapping, but not by other mappings, even when they use the same name. Within [interpolation functions](/docs/configuration/interpolation#bloblang-queries) a filter is scoped to the `${! }` block that uses it. Filters are not backed by resources and are therefore not shared between components, nor do they survive a restart.\n\nThe filter is sized by an optional capacity (default 10000) and a target false positive rate (default 0.01), which are fixed by the first call that creates the filter.",
+		NewExampleSpec("",
+			`root = this
+root.duplicate = this.id.bloom_add("ids")`,
+			`{"id":"foo"}`,
+			`{"duplicate":false,"id":"foo"}`,
+			`{"id":"bar"}`,
+			`{"duplicate":false,"id":"bar"}`,
+			`{"id":"foo"}`,
+			`{"duplicate":true,"id":"foo"}`,
+		),
+		NewExampleSpec("A filter can be sized for a larger number of values with a lower false positive rate.",
+			`root.duplicate = this.id.bloom_add("ids", 1000000, 0.001)`,
+			`{"id":"foo"}`,
+			`{"duplicate":false}`,
+			`{"id":"foo"}`,
+			`{"duplicate":true}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		bArgs, err := parseBloomFilterArgs(args...)
+		if err != nil {
+			return nil, err
+		}
+		local := NewSharedState()
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			b, err := getBloomFilter(ctx, local, bArgs)
+			if err != nil {
+				return nil, err
+			}
+			return b.add(IToBytes(v)), nil
+		}, nil
+	},
+	true,
+	ExpectBetweenNAndMArgs(1, 3),
+	ExpectStringArg(0),
+	ExpectIntArg(1),
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"bloom_test", "",
+	).InCategory(
+		MethodCategoryDeduplication,
+		"Returns a boolean indicating whether a value is probably present within a named bloom filter populated with [`bloom_add`](#bloom_add) by the same mapping, without modifying the filter. The optional capacity and false positive rate arguments are the same as `bloom_add`.",
+		NewExampleSpec("",
+			`root.seen_before = this.id.bloom_test("ids")
+root.seen_now = this.id.bloom_add("ids")`,
+			`{"id":"foo"}`,
+			`{"seen_before":false,"seen_now":false}`,
+			`{"id":"foo"}`,
+			`{"seen_before":true,"seen_now":true}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		bArgs, err := parseBloomFilterArgs(args...)
+		if err != nil {
+			return nil, err
+		}
+		local := NewSharedState()
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			b, err := getBloomFilter(ctx, local, bArgs)
+			if err != nil {
+				return nil, err
+			}
+			return b.test(IToBytes(v)), nil
+		}, nil
+	},
+	true,
+	ExpectBetweenNAndMArgs(1, 3),
+	ExpectStringArg(0),
+	ExpectIntArg(1),
+)
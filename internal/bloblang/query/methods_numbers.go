@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strings"
 )
 
 //------------------------------------------------------------------------------
@@ -263,3 +264,256 @@ var _ = registerSimpleMethod(
 	false,
 	ExpectNArgs(0),
 )
+
+//------------------------------------------------------------------------------
+
+var romanNumerals = []struct {
+	value   int64
+	numeral string
+}{
+	{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"},
+	{100, "C"}, {90, "XC"}, {50, "L"}, {40, "XL"},
+	{10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+}
+
+func toRoman(n int64) string {
+	var b strings.Builder
+	for _, r := range romanNumerals {
+		for n >= r.value {
+			b.WriteString(r.numeral)
+			n -= r.value
+		}
+	}
+	return b.String()
+}
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"to_roman", "",
+	).InCategory(
+		MethodCategoryNumbers,
+		"Converts an integer between 1 and 3999 into a Roman numeral string.",
+		NewExampleSpec("",
+			`root.volume = this.volume.to_roman()`,
+			`{"volume":1994}`,
+			`{"volume":"MCMXCIV"}`,
+		),
+	),
+	func(...interface{}) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			f, err := IGetNumber(v)
+			if err != nil {
+				return nil, err
+			}
+			if f != math.Trunc(f) || f < 1 || f > 3999 {
+				return nil, fmt.Errorf("expected an integer between 1 and 3999, got %v", f)
+			}
+			return toRoman(int64(f)), nil
+		}, nil
+	},
+	false,
+	ExpectNArgs(0),
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"from_roman", "",
+	).InCategory(
+		MethodCategoryNumbers,
+		"Parses a Roman numeral string into an integer. The numeral must be in standard subtractive form and represent a value between 1 and 3999, otherwise an error is returned. Lowercase numerals are accepted.",
+		NewExampleSpec("",
+			`root.volume = this.volume.from_roman()`,
+			`{"volume":"MCMXCIV"}`,
+			`{"volume":1994}`,
+		),
+	),
+	func(...interface{}) (simpleMethod, error) {
+		return stringMethod(func(s string) (interface{}, error) {
+			numeral := strings.ToUpper(s)
+			var n int64
+			rest := numeral
+			for _, r := range romanNumerals {
+				for strings.HasPrefix(rest, r.numeral) {
+					n += r.value
+					rest = rest[len(r.numeral):]
+				}
+			}
+			if rest != "" || n < 1 || n > 3999 || toRoman(n) != numeral {
+				return nil, fmt.Errorf("invalid roman numeral: %v", s)
+			}
+			return n, nil
+		}), nil
+	},
+	false,
+	ExpectNArgs(0),
+)
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"ordinal", "",
+	).InCategory(
+		MethodCategoryNumbers,
+		"Converts an integer into an English ordinal string such as `1st`, `2nd` or `3rd`.",
+		NewExampleSpec("",
+			`root.places = this.ranks.map_each(r -> r.ordinal())`,
+			`{"ranks":[1,2,3,4,11,12,13,21,102]}`,
+			`{"places":["1st","2nd","3rd","4th","11th","12th","13th","21st","102nd"]}`,
+		),
+	),
+	func(...interface{}) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			f, err := IGetNumber(v)
+			if err != nil {
+				return nil, err
+			}
+			if f != math.Trunc(f) {
+				return nil, fmt.Errorf("expected an integer, got %v", f)
+			}
+			n := int64(f)
+			abs := n
+			if abs < 0 {
+				abs = -abs
+			}
+			suffix := "th"
+			if abs%100 < 11 || abs%100 > 13 {
+				switch abs % 10 {
+				case 1:
+					suffix = "st"
+				case 2:
+					suffix = "nd"
+				case 3:
+					suffix = "rd"
+				}
+			}
+			return fmt.Sprintf("%v%v", n, suffix), nil
+		}, nil
+	},
+	false,
+	ExpectNArgs(0),
+)
+
+//------------------------------------------------------------------------------
+
+var (
+	numberWordsSmall = []string{
+		"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen",
+		"seventeen", "eighteen", "nineteen",
+	}
+	numberWordsTens   = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	numberWordsScales = []string{"", "thousand", "million", "billion", "trillion", "quadrillion", "quintillion"}
+)
+
+func numberToWords(n uint64) string {
+	if n == 0 {
+		return numberWordsSmall[0]
+	}
+	var groups []string
+	for scale := 0; n > 0; scale++ {
+		group := n % 1000
+		n /= 1000
+		if group == 0 {
+			continue
+		}
+		var words []string
+		if group >= 100 {
+			words = append(words, numberWordsSmall[group/100], "hundred")
+			group %= 100
+		}
+		if group >= 20 {
+			tens := numberWordsTens[group/10]
+			if group%10 > 0 {
+				tens += "-" + numberWordsSmall[group%10]
+			}
+			words = append(words, tens)
+		} else if group > 0 {
+			words = append(words, numberWordsSmall[group])
+		}
+		if scale > 0 {
+			words = append(words, numberWordsScales[scale])
+		}
+		groups = append([]string{strings.Join(words, " ")}, groups...)
+	}
+	return strings.Join(groups, " ")
+}
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"number_to_words", "",
+	).InCategory(
+		MethodCategoryNumbers,
+		"Converts an integer into English words. An optional boolean argument can be set `true` in order to write the number as an amount of currency, where the fractional part is rounded to the nearest cent.",
+		NewExampleSpec("",
+			`root.words = this.amount.number_to_words()`,
+			`{"amount":1200}`,
+			`{"words":"one thousand two hundred"}`,
+			`{"amount":-45}`,
+			`{"words":"minus forty-five"}`,
+		),
+		NewExampleSpec("",
+			`root.words = this.amount.number_to_words(true)`,
+			`{"amount":1200.5}`,
+			`{"words":"one thousand two hundred dollars and fifty cents"}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		currency := len(args) > 0 && args[0].(bool)
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			var whole, cents uint64
+			var negative bool
+			switch t := ISanitize(v).(type) {
+			case int64:
+				if negative = t < 0; negative {
+					whole = uint64(-(t + 1)) + 1
+				} else {
+					whole = uint64(t)
+				}
+			case uint64:
+				whole = t
+			default:
+				f, err := IGetNumber(v)
+				if err != nil {
+					return nil, err
+				}
+				if !currency && f != math.Trunc(f) {
+					return nil, fmt.Errorf("expected an integer, got %v", f)
+				}
+				if negative = f < 0; negative {
+					f = -f
+				}
+				f = math.Round(f * 100)
+				if f >= math.Exp2(64) {
+					return nil, fmt.Errorf("number is too large to convert: %v", v)
+				}
+				whole, cents = uint64(f)/100, uint64(f)%100
+				negative = negative && (whole > 0 || cents > 0)
+			}
+
+			words := numberToWords(whole)
+			if currency {
+				if whole == 1 {
+					words += " dollar"
+				} else {
+					words += " dollars"
+				}
+				if cents > 0 {
+					words += " and " + numberToWords(cents)
+					if cents == 1 {
+						words += " cent"
+					} else {
+						words += " cents"
+					}
+				}
+			}
+			if negative {
+				words = "minus " + words
+			}
+			return words, nil
+		}, nil
+	},
+	true,
+	ExpectOneOrZeroArgs(),
+	ExpectBoolArg(0),
+)
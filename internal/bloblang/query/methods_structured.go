@@ -2,13 +2,17 @@ package query
 
 import (
 	"bytes"
+	"container/heap"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/Jeffail/gabs/v2"
+	"github.com/google/go-cmp/cmp"
 	jsonschema "github.com/xeipuuv/gojsonschema"
 )
 
@@ -147,6 +151,88 @@ var _ = registerSimpleMethod(
 
 //------------------------------------------------------------------------------
 
+const defaultCartesianProductMax = 10000
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"cartesian_product", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Takes an array of arrays and returns an array of every combination that can be formed by taking one element from each, in order. If the input array or any of its arrays is empty the result is an empty array. In order to avoid exhausting memory an error is returned when the number of combinations would exceed a maximum, which defaults to 10000 and can be changed with an optional argument.",
+		NewExampleSpec("",
+			`root.combinations = [ this.sizes, this.colors ].cartesian_product()`,
+			`{"sizes":["S","M"],"colors":["red","blue"]}`,
+			`{"combinations":[["S","red"],["S","blue"],["M","red"],["M","blue"]]}`,
+		),
+		NewExampleSpec("",
+			`root.combinations = this.sets.cartesian_product(3)`,
+			`{"sets":[[1,2],[3,4]]}`,
+			`Error("failed assignment (line 1): field `+"`this.sets`"+`: cartesian product exceeds the maximum of 3 combinations")`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		max := int64(defaultCartesianProductMax)
+		if len(args) > 0 {
+			if max = args[0].(int64); max < 1 {
+				return nil, fmt.Errorf("maximum number of combinations must be greater than zero, got %v", max)
+			}
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			sets, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			if len(sets) == 0 {
+				return []interface{}{}, nil
+			}
+
+			arrs := make([][]interface{}, len(sets))
+			total := int64(1)
+			for i, set := range sets {
+				if arrs[i], ok = set.([]interface{}); !ok {
+					return nil, fmt.Errorf("index %v: %w", i, NewTypeError(set, ValueArray))
+				}
+				if len(arrs[i]) == 0 {
+					return []interface{}{}, nil
+				}
+				if total > max/int64(len(arrs[i])) {
+					return nil, fmt.Errorf("cartesian product exceeds the maximum of %v combinations", max)
+				}
+				total *= int64(len(arrs[i]))
+			}
+
+			results := make([]interface{}, 0, total)
+			indexes := make([]int, len(arrs))
+			for {
+				combination := make([]interface{}, len(arrs))
+				for i, j := range indexes {
+					combination[i] = IClone(arrs[i][j])
+				}
+				results = append(results, combination)
+
+				// Increment the indexes like an odometer, where the last array
+				// is cycled through fastest.
+				i := len(indexes) - 1
+				for ; i >= 0; i-- {
+					if indexes[i]++; indexes[i] < len(arrs[i]) {
+						break
+					}
+					indexes[i] = 0
+				}
+				if i < 0 {
+					break
+				}
+			}
+			return results, nil
+		}, nil
+	},
+	true,
+	ExpectOneOrZeroArgs(),
+	ExpectIntArg(0),
+)
+
+//------------------------------------------------------------------------------
+
 var _ = registerSimpleMethod(
 	NewMethodSpec(
 		"collapse", "",
@@ -185,6 +271,75 @@ var _ = registerSimpleMethod(
 
 //------------------------------------------------------------------------------
 
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"compact", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Recursively removes `null` values from an object or array, including object fields with a `null` value and `null` elements of arrays. An optional boolean argument can be set `true` in order to also remove empty strings, arrays and objects, including those left empty once their contents have been removed. The original value is not modified.",
+		NewExampleSpec("",
+			`root = this.compact()`,
+			`{"a":null,"b":{"c":null,"d":"foo"},"e":[1,null,2],"f":""}`,
+			`{"b":{"d":"foo"},"e":[1,2],"f":""}`,
+		),
+		NewExampleSpec("",
+			`root = this.compact(true)`,
+			`{"a":null,"b":{"c":null,"d":""},"e":[[],{}],"f":"bar"}`,
+			`{"f":"bar"}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		removeEmpty := false
+		if len(args) > 0 {
+			removeEmpty = args[0].(bool)
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			switch v.(type) {
+			case map[string]interface{}, []interface{}:
+				res, _ := compactValue(v, removeEmpty)
+				return res, nil
+			}
+			return nil, NewTypeError(v, ValueObject, ValueArray)
+		}, nil
+	},
+	true,
+	ExpectOneOrZeroArgs(),
+	ExpectBoolArg(0),
+)
+
+// compactValue returns a copy of v with null values removed, and empty values
+// also removed when removeEmpty is true. The returned boolean is false when v
+// itself should be removed.
+func compactValue(v interface{}, removeEmpty bool) (interface{}, bool) {
+	switch t := v.(type) {
+	case nil:
+		return nil, false
+	case string:
+		return t, !removeEmpty || t != ""
+	case []byte:
+		return t, !removeEmpty || len(t) > 0
+	case map[string]interface{}:
+		obj := make(map[string]interface{}, len(t))
+		for k, ele := range t {
+			if res, keep := compactValue(ele, removeEmpty); keep {
+				obj[k] = res
+			}
+		}
+		return obj, !removeEmpty || len(obj) > 0
+	case []interface{}:
+		arr := make([]interface{}, 0, len(t))
+		for _, ele := range t {
+			if res, keep := compactValue(ele, removeEmpty); keep {
+				arr = append(arr, res)
+			}
+		}
+		return arr, !removeEmpty || len(arr) > 0
+	}
+	return v, true
+}
+
+//------------------------------------------------------------------------------
+
 var _ = registerSimpleMethod(
 	NewMethodSpec(
 		"contains", "",
@@ -243,6 +398,39 @@ var _ = registerSimpleMethod(
 
 //------------------------------------------------------------------------------
 
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"ensure_array", "",
+	).InCategory(
+		MethodCategoryCoercion,
+		"Ensures that a value is an array, where arrays are returned unchanged, `null` becomes an empty array and any other value is wrapped in an array containing only that value. This is useful for fields that are sometimes a single value and sometimes an array of values.",
+		NewExampleSpec("",
+			`root.tags = this.tags.ensure_array()`,
+			`{"tags":"foo"}`,
+			`{"tags":["foo"]}`,
+			`{"tags":["foo","bar"]}`,
+			`{"tags":["foo","bar"]}`,
+			`{"tags":null}`,
+			`{"tags":[]}`,
+		),
+	),
+	func(...interface{}) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			switch t := v.(type) {
+			case []interface{}:
+				return t, nil
+			case nil:
+				return []interface{}{}, nil
+			}
+			return []interface{}{v}, nil
+		}, nil
+	},
+	false,
+	ExpectNArgs(0),
+)
+
+//------------------------------------------------------------------------------
+
 var _ = registerSimpleMethod(
 	NewMethodSpec(
 		"enumerated",
@@ -531,6 +719,205 @@ var _ = registerSimpleMethod(
 
 //------------------------------------------------------------------------------
 
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"fill_forward", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Replaces `null` elements of an array with the most recent non-null element before them, which is commonly known as last observation carried forward. Leading `null` elements have no prior value and are left as they are. See [`fill_back`](#fill_back) for the reverse.",
+		NewExampleSpec("",
+			`root.filled = this.series.fill_forward()`,
+			`{"series":[null,1,null,null,4,null]}`,
+			`{"filled":[null,1,1,1,4,4]}`,
+		),
+	),
+	func(...interface{}) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			values, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			result := make([]interface{}, len(values))
+			var last interface{}
+			for i, ele := range values {
+				if ele != nil {
+					last = ele
+				}
+				result[i] = last
+			}
+			return result, nil
+		}, nil
+	},
+	false,
+	ExpectNArgs(0),
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"fill_back", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Replaces `null` elements of an array with the nearest non-null element after them. Trailing `null` elements have no following value and are left as they are. See [`fill_forward`](#fill_forward) for the reverse.",
+		NewExampleSpec("",
+			`root.filled = this.series.fill_back()`,
+			`{"series":[null,1,null,null,4,null]}`,
+			`{"filled":[1,1,4,4,4,null]}`,
+		),
+	),
+	func(...interface{}) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			values, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			result := make([]interface{}, len(values))
+			var next interface{}
+			for i := len(values) - 1; i >= 0; i-- {
+				if values[i] != nil {
+					next = values[i]
+				}
+				result[i] = next
+			}
+			return result, nil
+		}, nil
+	},
+	false,
+	ExpectNArgs(0),
+)
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"first", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the first element of an array. If the array is empty then `null` is returned, or the value of an optional argument if one is provided.",
+		NewExampleSpec("",
+			`root.first = this.items.first()
+root.first_or = this.others.first("none")`,
+			`{"items":["foo","bar"],"others":[]}`,
+			`{"first":"foo","first_or":"none"}`,
+		),
+	),
+	arrayEndMethod(false),
+	true,
+	ExpectOneOrZeroArgs(),
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"last", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the last element of an array. If the array is empty then `null` is returned, or the value of an optional argument if one is provided.",
+		NewExampleSpec("",
+			`root.last = this.items.last()
+root.last_or = this.others.last("none")`,
+			`{"items":["foo","bar"],"others":[]}`,
+			`{"last":"bar","last_or":"none"}`,
+		),
+	),
+	arrayEndMethod(true),
+	true,
+	ExpectOneOrZeroArgs(),
+)
+
+func arrayEndMethod(last bool) func(args ...interface{}) (simpleMethod, error) {
+	return func(args ...interface{}) (simpleMethod, error) {
+		var defaultValue interface{}
+		if len(args) > 0 {
+			defaultValue = args[0]
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			arr, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			if len(arr) == 0 {
+				return defaultValue, nil
+			}
+			if last {
+				return arr[len(arr)-1], nil
+			}
+			return arr[0], nil
+		}, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"histogram", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Counts the numerical values of an array into buckets defined by an array of ascending boundaries, returning an object containing the count of each bucket along with the number of values that fell below the first boundary (`underflow`) and at or above the last boundary (`overflow`). Each bucket includes its lower boundary and excludes its upper boundary, therefore a value that is exactly on a boundary is counted in the bucket above it.",
+		NewExampleSpec("",
+			`root.histogram = this.values.histogram([0,10,20,30])`,
+			`{"values":[-2,0,4,10,15,19,20,30,42]}`,
+			`{"histogram":{"buckets":[2,3,1],"overflow":2,"underflow":1}}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		boundsArg, ok := args[0].([]interface{})
+		if !ok {
+			return nil, NewTypeError(args[0], ValueArray)
+		}
+		if len(boundsArg) < 2 {
+			return nil, fmt.Errorf("expected at least two bucket boundaries, got %v", len(boundsArg))
+		}
+		bounds := make([]float64, len(boundsArg))
+		for i, b := range boundsArg {
+			var err error
+			if bounds[i], err = IGetNumber(b); err != nil {
+				return nil, fmt.Errorf("boundary %v: %w", i, err)
+			}
+			if i > 0 && bounds[i] <= bounds[i-1] {
+				return nil, fmt.Errorf("bucket boundaries must be in ascending order, boundary %v (%v) is not greater than %v", i, bounds[i], bounds[i-1])
+			}
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			values, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			counts := make([]int64, len(bounds)-1)
+			var underflow, overflow int64
+			for i, ele := range values {
+				n, err := IGetNumber(ele)
+				if err != nil {
+					return nil, fmt.Errorf("index %v: %w", i, err)
+				}
+				bucket := sort.Search(len(bounds), func(j int) bool {
+					return bounds[j] > n
+				}) - 1
+				switch {
+				case bucket < 0:
+					underflow++
+				case bucket >= len(counts):
+					overflow++
+				default:
+					counts[bucket]++
+				}
+			}
+			buckets := make([]interface{}, len(counts))
+			for i, c := range counts {
+				buckets[i] = c
+			}
+			return map[string]interface{}{
+				"buckets":   buckets,
+				"underflow": underflow,
+				"overflow":  overflow,
+			}, nil
+		}, nil
+	},
+	true,
+	ExpectNArgs(1),
+)
+
+//------------------------------------------------------------------------------
+
 var _ = registerSimpleMethod(
 	NewMethodSpec(
 		"index",
@@ -584,60 +971,167 @@ var _ = registerSimpleMethod(
 
 var _ = registerSimpleMethod(
 	NewMethodSpec(
-		"json_schema",
-		"Checks a [JSON schema](https://json-schema.org/) against a value and returns the value if it matches or throws and error if it does not.",
+		"interpolate_missing", "",
 	).InCategory(
 		MethodCategoryObjectAndArray,
-		"",
+		"Replaces `null` elements of an array of numbers with values linearly interpolated between the nearest non-null elements either side of them. Leading and trailing `null` elements have no neighbour to interpolate from and are left as they are, unless an optional boolean argument is set `true`, in which case they are filled with the nearest non-null value.",
 		NewExampleSpec("",
-			`root = this.json_schema("""{
-  "type":"object",
-  "properties":{
-    "foo":{
-      "type":"string"
-    }
-  }
-}""")`,
-			`{"foo":"bar"}`,
-			`{"foo":"bar"}`,
-			`{"foo":5}`,
-			`Error("failed assignment (line 1): field `+"`this`"+`: foo invalid type. expected: string, given: integer")`,
+			`root.filled = this.readings.interpolate_missing()`,
+			`{"readings":[null,1,null,null,4,null]}`,
+			`{"filled":[null,1,2,3,4,null]}`,
 		),
-		NewExampleSpec(
-			"In order to load a schema from a file use the `file` function.",
-			`root = this.json_schema(file(var("BENTHOS_TEST_BLOBLANG_SCHEMA_FILE")))`,
+		NewExampleSpec("",
+			`root.filled = this.readings.interpolate_missing(true)`,
+			`{"readings":[null,1,null,null,4,null]}`,
+			`{"filled":[1,1,2,3,4,4]}`,
 		),
-	).Beta(),
+	),
 	func(args ...interface{}) (simpleMethod, error) {
-		schema, err := jsonschema.NewSchema(jsonschema.NewStringLoader(args[0].(string)))
-		if err != nil {
-			return nil, fmt.Errorf("failed to parse json schema definition: %w", err)
+		fillEdges := false
+		if len(args) > 0 {
+			fillEdges = args[0].(bool)
 		}
-		return func(res interface{}, ctx FunctionContext) (interface{}, error) {
-			result, err := schema.Validate(jsonschema.NewGoLoader(res))
-			if err != nil {
-				return nil, err
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			values, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
 			}
-			if !result.Valid() {
-				var errStr string
-				for i, desc := range result.Errors() {
-					if i > 0 {
-						errStr = errStr + "\n"
+			result := make([]interface{}, len(values))
+			copy(result, values)
+
+			prev := -1
+			var prevN float64
+			for i, ele := range values {
+				if ele == nil {
+					continue
+				}
+				n, err := IGetNumber(ele)
+				if err != nil {
+					return nil, fmt.Errorf("index %v: %w", i, err)
+				}
+				if prev == -1 {
+					if fillEdges {
+						for j := 0; j < i; j++ {
+							result[j] = ele
+						}
 					}
-					description := strings.ToLower(desc.Description())
-					if property := desc.Details()["property"]; property != nil {
-						description = property.(string) + strings.TrimPrefix(description, strings.ToLower(property.(string)))
+				} else {
+					step := (n - prevN) / float64(i-prev)
+					for j := prev + 1; j < i; j++ {
+						result[j] = prevN + step*float64(j-prev)
 					}
-					errStr = errStr + desc.Field() + " " + description
 				}
-				return nil, errors.New(errStr)
+				prev, prevN = i, n
 			}
-			return res, nil
+			if fillEdges && prev != -1 {
+				for j := prev + 1; j < len(values); j++ {
+					result[j] = values[prev]
+				}
+			}
+			return result, nil
 		}, nil
 	},
 	true,
-	ExpectNArgs(1),
-	ExpectStringArg(0),
+	ExpectOneOrZeroArgs(),
+	ExpectBoolArg(0),
+)
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"intersperse", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns a new array with the argument inserted between each pair of adjacent elements of an array. Arrays with fewer than two elements are returned without any separators added.",
+		NewExampleSpec("",
+			`root.items = this.items.intersperse("and")`,
+			`{"items":["foo","bar","baz"]}`,
+			`{"items":["foo","and","bar","and","baz"]}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		sep := args[0]
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			values, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			if len(values) == 0 {
+				return []interface{}{}, nil
+			}
+			result := make([]interface{}, 0, len(values)*2-1)
+			for i, ele := range values {
+				if i > 0 {
+					result = append(result, IClone(sep))
+				}
+				result = append(result, ele)
+			}
+			return result, nil
+		}, nil
+	},
+	true,
+	ExpectNArgs(1),
+)
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"json_schema",
+		"Checks a [JSON schema](https://json-schema.org/) against a value and returns the value if it matches or throws and error if it does not.",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"",
+		NewExampleSpec("",
+			`root = this.json_schema("""{
+  "type":"object",
+  "properties":{
+    "foo":{
+      "type":"string"
+    }
+  }
+}""")`,
+			`{"foo":"bar"}`,
+			`{"foo":"bar"}`,
+			`{"foo":5}`,
+			`Error("failed assignment (line 1): field `+"`this`"+`: foo invalid type. expected: string, given: integer")`,
+		),
+		NewExampleSpec(
+			"In order to load a schema from a file use the `file` function.",
+			`root = this.json_schema(file(var("BENTHOS_TEST_BLOBLANG_SCHEMA_FILE")))`,
+		),
+	).Beta(),
+	func(args ...interface{}) (simpleMethod, error) {
+		schema, err := jsonschema.NewSchema(jsonschema.NewStringLoader(args[0].(string)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse json schema definition: %w", err)
+		}
+		return func(res interface{}, ctx FunctionContext) (interface{}, error) {
+			result, err := schema.Validate(jsonschema.NewGoLoader(res))
+			if err != nil {
+				return nil, err
+			}
+			if !result.Valid() {
+				var errStr string
+				for i, desc := range result.Errors() {
+					if i > 0 {
+						errStr = errStr + "\n"
+					}
+					description := strings.ToLower(desc.Description())
+					if property := desc.Details()["property"]; property != nil {
+						description = property.(string) + strings.TrimPrefix(description, strings.ToLower(property.(string)))
+					}
+					errStr = errStr + desc.Field() + " " + description
+				}
+				return nil, errors.New(errStr)
+			}
+			return res, nil
+		}, nil
+	},
+	true,
+	ExpectNArgs(1),
+	ExpectStringArg(0),
 )
 
 //------------------------------------------------------------------------------
@@ -751,6 +1245,144 @@ var _ = registerSimpleMethod(
 
 //------------------------------------------------------------------------------
 
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"json_size", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the number of bytes that a value would occupy when serialized as minified JSON. Multi-byte characters within strings are counted by their encoded size.",
+		NewExampleSpec("",
+			`root.size = this.doc.json_size()`,
+			`{"doc":{"name":"café","tags":["a","b"]}}`,
+			`{"size":33}`,
+		),
+		NewExampleSpec("Values can be removed when they exceed a size limit.",
+			`root = this
+root.payload = if this.payload.json_size() > 20 { deleted() }`,
+			`{"id":"foo","payload":{"values":[1,2,3]}}`,
+			`{"id":"foo","payload":{"values":[1,2,3]}}`,
+			`{"id":"bar","payload":{"values":[1,2,3,4,5,6,7,8,9]}}`,
+			`{"id":"bar"}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			if b, ok := v.([]byte); ok {
+				v = string(b)
+			}
+			var counter jsonSizeCounter
+			if err := json.NewEncoder(&counter).Encode(v); err != nil {
+				return nil, fmt.Errorf("failed to serialize value: %w", err)
+			}
+			// Remove the trailing newline added by the encoder.
+			return int64(counter) - 1, nil
+		}, nil
+	},
+	false,
+	ExpectNArgs(0),
+)
+
+// jsonSizeCounter is an io.Writer that only counts the bytes written to it.
+type jsonSizeCounter int64
+
+func (c *jsonSizeCounter) Write(p []byte) (int, error) {
+	*c += jsonSizeCounter(len(p))
+	return len(p), nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"depth", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the maximum nesting depth of objects and arrays within a value. An object or array containing only scalar values, or nothing at all, has a depth of one, each level of nesting within it adds one, and scalar values have a depth of zero.",
+		NewExampleSpec("",
+			`root.depth = this.depth()`,
+			`{"foo":"bar"}`,
+			`{"depth":1}`,
+			`{"foo":{"bar":[1,2,{"baz":"buz"}]}}`,
+			`{"depth":4}`,
+		),
+		NewExampleSpec("",
+			`root = if this.depth() > 3 { throw("document is too deeply nested") }`,
+			`{"a":{"b":{"c":{"d":"e"}}}}`,
+			`Error("failed assignment (line 1): document is too deeply nested")`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			return valueDepth(v), nil
+		}, nil
+	},
+	false,
+	ExpectNArgs(0),
+)
+
+func valueDepth(v interface{}) int64 {
+	var maxChild int64
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, child := range t {
+			if d := valueDepth(child); d > maxChild {
+				maxChild = d
+			}
+		}
+	case []interface{}:
+		for _, child := range t {
+			if d := valueDepth(child); d > maxChild {
+				maxChild = d
+			}
+		}
+	default:
+		return 0
+	}
+	return maxChild + 1
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"count_leaves", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the total number of scalar values within a structure, searching through all nested objects and arrays. Empty objects and arrays contain no leaves, and a scalar value counts as a single leaf.",
+		NewExampleSpec("",
+			`root.leaves = this.count_leaves()`,
+			`{"foo":{"bar":[1,2,3],"baz":{}},"buz":null}`,
+			`{"leaves":4}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			return countLeaves(v), nil
+		}, nil
+	},
+	false,
+	ExpectNArgs(0),
+)
+
+func countLeaves(v interface{}) int64 {
+	var count int64
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, child := range t {
+			count += countLeaves(child)
+		}
+	case []interface{}:
+		for _, child := range t {
+			count += countLeaves(child)
+		}
+	default:
+		return 1
+	}
+	return count
+}
+
+//------------------------------------------------------------------------------
+
 var _ = registerSimpleMethod(
 	NewMethodSpec(
 		"map_each", "",
@@ -836,6 +1468,85 @@ Apply a mapping to each value of an object and replace the value with the result
 
 //------------------------------------------------------------------------------
 
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"walk_leaves", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Apply a mapping to every scalar value of a structure, searching through all nested objects and arrays, and replace each value with the result. Within the argument mapping the context is the value being mapped. Objects and arrays are reconstructed around the mapped values, and a leaf is removed from its parent when the mapping results in `deleted()`.",
+		NewExampleSpec("",
+			`root = this.walk_leaves(v -> if v.type() == "string" { v.trim() } else { v })`,
+			`{"name":"  foo ","tags":[" bar","baz  "],"meta":{"count":3,"note":" hi "}}`,
+			`{"meta":{"count":3,"note":"hi"},"name":"foo","tags":["bar","baz"]}`,
+		),
+		NewExampleSpec("",
+			`root = this.walk_leaves(v -> if v.type() == "null" { deleted() } else { v })`,
+			`{"a":null,"b":[1,null,2],"c":{"d":null,"e":"f"}}`,
+			`{"b":[1,2],"c":{"e":"f"}}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		mapFn, ok := args[0].(Function)
+		if !ok {
+			return nil, fmt.Errorf("expected query argument, received %T", args[0])
+		}
+		return func(res interface{}, ctx FunctionContext) (interface{}, error) {
+			return walkLeaves(res, "", mapFn, ctx)
+		}, nil
+	},
+	false,
+	ExpectNArgs(1),
+	ExpectFunctionArg(0),
+)
+
+func walkLeaves(v interface{}, path string, mapFn Function, ctx FunctionContext) (interface{}, error) {
+	childPath := func(seg string) string {
+		if path == "" {
+			return seg
+		}
+		return path + "." + seg
+	}
+	switch t := v.(type) {
+	case []interface{}:
+		newSlice := make([]interface{}, 0, len(t))
+		for i, e := range t {
+			newV, err := walkLeaves(e, childPath(strconv.Itoa(i)), mapFn, ctx)
+			if err != nil {
+				return nil, err
+			}
+			if _, isDelete := newV.(Delete); !isDelete {
+				newSlice = append(newSlice, newV)
+			}
+		}
+		return newSlice, nil
+	case map[string]interface{}:
+		newMap := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			newV, err := walkLeaves(e, childPath(k), mapFn, ctx)
+			if err != nil {
+				return nil, err
+			}
+			if _, isDelete := newV.(Delete); !isDelete {
+				newMap[k] = newV
+			}
+		}
+		return newMap, nil
+	}
+	newV, err := mapFn.Exec(ctx.WithValue(v))
+	if err != nil {
+		if path == "" {
+			return nil, ErrFrom(err, mapFn)
+		}
+		return nil, fmt.Errorf("failed to process leaf %v: %w", path, ErrFrom(err, mapFn))
+	}
+	if _, isNothing := newV.(Nothing); isNothing {
+		return v, nil
+	}
+	return newV, nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = registerSimpleMethod(
 	NewMethodSpec(
 		"map_each_key", "",
@@ -892,6 +1603,104 @@ var _ = registerSimpleMethod(
 
 //------------------------------------------------------------------------------
 
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"walk_keys", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Apply a mapping to every key of an object, searching through all nested objects and arrays, and replace each key with the result, which must be a string. When two keys of the same object are mapped to the same result an error is returned, unless the second argument `overwrite` is set to `true`, in which case the value of the key that sorts last lexicographically is kept.",
+		NewExampleSpec("",
+			`root = this.walk_keys(k -> k.trim("_"))`,
+			`{"_id":"foo","_meta":{"_tags":["a","b"],"count":2},"items":[{"_name":"bar"}]}`,
+			`{"id":"foo","items":[{"name":"bar"}],"meta":{"count":2,"tags":["a","b"]}}`,
+		),
+		NewExampleSpec("",
+			`root = this.walk_keys(k -> k.lowercase(), true)`,
+			`{"ID":"foo","id":"bar"}`,
+			`{"id":"bar"}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		mapFn, ok := args[0].(Function)
+		if !ok {
+			return nil, fmt.Errorf("expected query argument, received %T", args[0])
+		}
+		overwrite := false
+		if len(args) > 1 {
+			overwrite = args[1].(bool)
+		}
+		return func(res interface{}, ctx FunctionContext) (interface{}, error) {
+			return walkKeys(res, "", mapFn, overwrite, ctx)
+		}, nil
+	},
+	false,
+	ExpectBetweenNAndMArgs(1, 2),
+	ExpectFunctionArg(0),
+	ExpectBoolArg(1),
+)
+
+func walkKeys(v interface{}, path string, mapFn Function, overwrite bool, ctx FunctionContext) (interface{}, error) {
+	childPath := func(seg string) string {
+		if path == "" {
+			return seg
+		}
+		return path + "." + seg
+	}
+	switch t := v.(type) {
+	case []interface{}:
+		newSlice := make([]interface{}, len(t))
+		for i, e := range t {
+			newV, err := walkKeys(e, childPath(strconv.Itoa(i)), mapFn, overwrite, ctx)
+			if err != nil {
+				return nil, err
+			}
+			newSlice[i] = newV
+		}
+		return newSlice, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		newMap := make(map[string]interface{}, len(t))
+		origKeys := make(map[string]string, len(t))
+		for _, k := range keys {
+			var ctxVal interface{} = k
+			newKey, err := mapFn.Exec(ctx.WithValue(ctxVal))
+			if err != nil {
+				return nil, fmt.Errorf("failed to process key %v: %w", childPath(k), ErrFrom(err, mapFn))
+			}
+
+			var keyStr string
+			switch kt := newKey.(type) {
+			case Nothing:
+				keyStr = k
+			case string:
+				keyStr = kt
+			default:
+				return nil, fmt.Errorf("unexpected result from key mapping of %v: %w", childPath(k), NewTypeError(newKey, ValueString))
+			}
+
+			if prev, exists := origKeys[keyStr]; exists && !overwrite {
+				return nil, fmt.Errorf("keys %v and %v both map to %v", childPath(prev), childPath(k), keyStr)
+			}
+			origKeys[keyStr] = k
+
+			newV, err := walkKeys(t[k], childPath(k), mapFn, overwrite, ctx)
+			if err != nil {
+				return nil, err
+			}
+			newMap[keyStr] = newV
+		}
+		return newMap, nil
+	}
+	return v, nil
+}
+
+//------------------------------------------------------------------------------
+
 var _ = registerMethod(
 	NewMethodSpec(
 		"merge", "Merge a source object into an existing destination object. When a collision is found within the merged structures (both a source and destination object contain the same non-object keys) the result will be an array containing both values, where values that are already arrays will be expanded into the resulting array.",
@@ -952,13 +1761,137 @@ func mergeMethod(target Function, args ...interface{}) (Function, error) {
 
 var _ = registerSimpleMethod(
 	NewMethodSpec(
-		"not_empty", "",
+		"moving_average", "",
 	).InCategory(
-		MethodCategoryCoercion,
-		"Ensures that the given string, array or object value is not empty, and if so returns it, otherwise an error is returned.",
+		MethodCategoryObjectAndArray,
+		"Calculates the simple moving average of an array of numbers over a trailing window of a given size, returning an array of the same length. By default the elements before the first full window are `null`, an optional second boolean argument can be set `true` in order to average them over the elements available instead.",
 		NewExampleSpec("",
-			`root.a = this.a.not_empty()`,
-			`{"a":"foo"}`,
+			`root.smoothed = this.series.moving_average(3)
+root.partial = this.series.moving_average(3, true)`,
+			`{"series":[2,4,6,8,10]}`,
+			`{"partial":[2,3,4,6,8],"smoothed":[null,null,4,6,8]}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		window := args[0].(int64)
+		if window < 1 {
+			return nil, fmt.Errorf("window size must be greater than zero, got %v", window)
+		}
+		partial := false
+		if len(args) > 1 {
+			partial = args[1].(bool)
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			values, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			nums := make([]float64, len(values))
+			for i, ele := range values {
+				var err error
+				if nums[i], err = IGetNumber(ele); err != nil {
+					return nil, fmt.Errorf("index %v: %w", i, err)
+				}
+			}
+			result := make([]interface{}, len(values))
+			for i := range nums {
+				start := i + 1 - int(window)
+				if start < 0 {
+					if !partial {
+						continue
+					}
+					start = 0
+				}
+				var total float64
+				for _, n := range nums[start : i+1] {
+					total += n
+				}
+				result[i] = total / float64(i+1-start)
+			}
+			return result, nil
+		}, nil
+	},
+	true,
+	ExpectBetweenNAndMArgs(1, 2),
+	ExpectIntArg(0),
+	ExpectBoolArg(1),
+)
+
+//------------------------------------------------------------------------------
+
+var _ = registerMethod(
+	NewMethodSpec(
+		"running_total", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Calculates the cumulative sum of an array of numbers, returning an array of the same length where each element is the sum of all elements up to and including it. An optional query argument can be provided in order to extract the number to sum from each element, which is useful for arrays of objects.",
+		NewExampleSpec("",
+			`root.totals = this.values.running_total()`,
+			`{"values":[1,2,3,4]}`,
+			`{"totals":[1,3,6,10]}`,
+		),
+		NewExampleSpec("",
+			`root.totals = this.orders.running_total(order -> order.amount)`,
+			`{"orders":[{"id":"a","amount":10},{"id":"b","amount":2.5},{"id":"c","amount":7}]}`,
+			`{"totals":[10,12.5,19.5]}`,
+		),
+	),
+	false, runningTotalMethod,
+	ExpectOneOrZeroArgs(),
+)
+
+func runningTotalMethod(target Function, args ...interface{}) (Function, error) {
+	var mapFn Function
+	if len(args) > 0 {
+		var ok bool
+		if mapFn, ok = args[0].(Function); !ok {
+			return nil, fmt.Errorf("expected query argument, received %T", args[0])
+		}
+	}
+
+	return ClosureFunction("method running_total", func(ctx FunctionContext) (interface{}, error) {
+		v, err := target.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeErrorFrom(target.Annotation(), v, ValueArray)
+		}
+
+		var total float64
+		totals := make([]interface{}, len(values))
+		for i, ele := range values {
+			if mapFn != nil {
+				if ele, err = mapFn.Exec(ctx.WithValue(ele)); err != nil {
+					return nil, fmt.Errorf("index %v: %w", i, err)
+				}
+			}
+			n, err := IGetNumber(ele)
+			if err != nil {
+				if mapFn != nil {
+					err = ErrFrom(err, mapFn)
+				}
+				return nil, fmt.Errorf("index %v: %w", i, err)
+			}
+			total += n
+			totals[i] = total
+		}
+		return totals, nil
+	}, aggregateTargetPaths(target, mapFn)), nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"not_empty", "",
+	).InCategory(
+		MethodCategoryCoercion,
+		"Ensures that the given string, array or object value is not empty, and if so returns it, otherwise an error is returned.",
+		NewExampleSpec("",
+			`root.a = this.a.not_empty()`,
+			`{"a":"foo"}`,
 			`{"a":"foo"}`,
 
 			`{"a":""}`,
@@ -1192,6 +2125,286 @@ func sortByMethod(target Function, args ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = registerMethod(
+	NewMethodSpec(
+		"top_k", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the `k` largest elements of an array in descending order. An optional query argument can be provided in order to emit the value that each element is ranked by, otherwise elements are ranked by their own value. The type of all ranked values must match, and both string and number values are supported. Elements with equal ranks retain their original order. If `k` is greater than the length of the array then all elements are returned.",
+		NewExampleSpec("",
+			`root.leaders = this.scores.top_k(2, s -> s.value)`,
+			`{"scores":[{"id":"a","value":5},{"id":"b","value":12},{"id":"c","value":9}]}`,
+			`{"leaders":[{"id":"b","value":12},{"id":"c","value":9}]}`,
+		),
+	),
+	false, topKMethod(true),
+	ExpectBetweenNAndMArgs(1, 2),
+)
+
+var _ = registerMethod(
+	NewMethodSpec(
+		"bottom_k", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the `k` smallest elements of an array in ascending order. An optional query argument can be provided in order to emit the value that each element is ranked by, otherwise elements are ranked by their own value. The type of all ranked values must match, and both string and number values are supported. Elements with equal ranks retain their original order. If `k` is greater than the length of the array then all elements are returned.",
+		NewExampleSpec("",
+			`root.fastest = this.laps.bottom_k(2)`,
+			`{"laps":[61.2,58.9,60.4,59.3]}`,
+			`{"fastest":[58.9,59.3]}`,
+		),
+	),
+	false, topKMethod(false),
+	ExpectBetweenNAndMArgs(1, 2),
+)
+
+type rankedElement struct {
+	index int
+	num   float64
+	str   string
+}
+
+// rankedHeap keeps the k best elements seen so far with the worst of them at
+// the root, so that it can be evicted when a better element is found.
+type rankedHeap struct {
+	elements []rankedElement
+	isString bool
+	largest  bool
+}
+
+// better returns true if element i should be ranked above element j.
+func (h *rankedHeap) better(i, j rankedElement) bool {
+	if h.isString {
+		if i.str != j.str {
+			return (i.str > j.str) == h.largest
+		}
+	} else if i.num != j.num {
+		return (i.num > j.num) == h.largest
+	}
+	return i.index < j.index
+}
+
+func (h *rankedHeap) Len() int           { return len(h.elements) }
+func (h *rankedHeap) Less(i, j int) bool { return h.better(h.elements[j], h.elements[i]) }
+func (h *rankedHeap) Swap(i, j int)      { h.elements[i], h.elements[j] = h.elements[j], h.elements[i] }
+
+func (h *rankedHeap) Push(x interface{}) {
+	h.elements = append(h.elements, x.(rankedElement))
+}
+
+func (h *rankedHeap) Pop() interface{} {
+	n := len(h.elements) - 1
+	e := h.elements[n]
+	h.elements = h.elements[:n]
+	return e
+}
+
+// rankOf returns the rank of an element of an array, which is either the
+// element itself or the result of mapFn applied to it, along with whether the
+// rank is a string.
+func rankOf(ctx FunctionContext, name string, mapFn Function, i int, v interface{}) (rankedElement, bool, error) {
+	rankErr := func(err error) error {
+		if mapFn != nil {
+			err = ErrFrom(err, mapFn)
+		}
+		return fmt.Errorf("%v element %v: %w", name, i, err)
+	}
+	if mapFn != nil {
+		var err error
+		if v, err = mapFn.Exec(ctx.WithValue(v)); err != nil {
+			return rankedElement{}, false, fmt.Errorf("%v element %v: %w", name, i, err)
+		}
+	}
+	switch t := v.(type) {
+	case float64, int, int64, uint64, json.Number:
+		n, err := IGetNumber(t)
+		if err != nil {
+			return rankedElement{}, false, rankErr(err)
+		}
+		return rankedElement{index: i, num: n}, false, nil
+	case string, []byte:
+		return rankedElement{index: i, str: IToString(t)}, true, nil
+	}
+	return rankedElement{}, false, rankErr(NewTypeError(v, ValueNumber, ValueString))
+}
+
+func checkRankType(name string, i int, expectString, isString bool) error {
+	if isString == expectString {
+		return nil
+	}
+	expected, got := ValueNumber, ValueString
+	if expectString {
+		expected, got = ValueString, ValueNumber
+	}
+	return fmt.Errorf("%v element %v: expected %v rank value, got %v", name, i, expected, got)
+}
+
+func topKMethod(largest bool) func(target Function, args ...interface{}) (Function, error) {
+	name := "bottom_k"
+	if largest {
+		name = "top_k"
+	}
+	return func(target Function, args ...interface{}) (Function, error) {
+		kArg := args[0]
+		if lit, ok := kArg.(*Literal); ok {
+			kArg = lit.Value
+		}
+		var mapFn Function
+		if len(args) > 1 {
+			var ok bool
+			if mapFn, ok = args[1].(Function); !ok {
+				return nil, fmt.Errorf("expected query argument, received %T", args[1])
+			}
+		}
+
+		targets := []Function{target}
+		if fn, ok := kArg.(Function); ok {
+			targets = append(targets, fn)
+		}
+		if mapFn != nil {
+			targets = append(targets, mapFn)
+		}
+
+		return ClosureFunction("method "+name, func(ctx FunctionContext) (interface{}, error) {
+			k := kArg
+			if fn, ok := kArg.(Function); ok {
+				var err error
+				if k, err = fn.Exec(ctx); err != nil {
+					return nil, err
+				}
+			}
+			n, err := IGetInt(k)
+			if err != nil {
+				return nil, err
+			}
+			if n < 0 {
+				return nil, fmt.Errorf("%v count must not be negative, got %v", name, n)
+			}
+
+			v, err := target.Exec(ctx)
+			if err != nil {
+				return nil, err
+			}
+			values, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeErrorFrom(target.Annotation(), v, ValueArray)
+			}
+
+			h := &rankedHeap{largest: largest}
+			for i, ele := range values {
+				r, isString, err := rankOf(ctx, name, mapFn, i, ele)
+				if err != nil {
+					return nil, err
+				}
+				if i == 0 {
+					h.isString = isString
+				} else if err := checkRankType(name, i, h.isString, isString); err != nil {
+					return nil, err
+				}
+				if int64(h.Len()) < n {
+					heap.Push(h, r)
+				} else if h.Len() > 0 && h.better(r, h.elements[0]) {
+					h.elements[0] = r
+					heap.Fix(h, 0)
+				}
+			}
+
+			result := make([]interface{}, h.Len())
+			for i := len(result) - 1; i >= 0; i-- {
+				result[i] = values[heap.Pop(h).(rankedElement).index]
+			}
+			return result, nil
+		}, aggregateTargetPaths(targets...)), nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerMethod(
+	NewMethodSpec(
+		"index_of_max", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the index of the largest element of an array, or `-1` if the array is empty. When several elements share the largest value the index of the first of them is returned. An optional query argument can be provided in order to emit the value that each element is compared by, otherwise elements are compared by their own value. The type of all compared values must match, and both string and number values are supported.",
+		NewExampleSpec("",
+			`root.best = this.scores.index_of_max()`,
+			`{"scores":[5,12,9,12]}`,
+			`{"best":1}`,
+		),
+		NewExampleSpec("",
+			`root.best = this.players.index_of_max(p -> p.score)`,
+			`{"players":[{"name":"a","score":5},{"name":"b","score":12},{"name":"c","score":9}]}`,
+			`{"best":1}`,
+		),
+	),
+	false, indexOfRankMethod(true),
+	ExpectOneOrZeroArgs(),
+)
+
+var _ = registerMethod(
+	NewMethodSpec(
+		"index_of_min", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns the index of the smallest element of an array, or `-1` if the array is empty. When several elements share the smallest value the index of the first of them is returned. An optional query argument can be provided in order to emit the value that each element is compared by, otherwise elements are compared by their own value. The type of all compared values must match, and both string and number values are supported.",
+		NewExampleSpec("",
+			`root.fastest = this.laps.index_of_min()`,
+			`{"laps":[61.2,58.9,60.4,58.9]}`,
+			`{"fastest":1}`,
+		),
+	),
+	false, indexOfRankMethod(false),
+	ExpectOneOrZeroArgs(),
+)
+
+func indexOfRankMethod(largest bool) func(target Function, args ...interface{}) (Function, error) {
+	name := "index_of_min"
+	if largest {
+		name = "index_of_max"
+	}
+	return func(target Function, args ...interface{}) (Function, error) {
+		var mapFn Function
+		if len(args) > 0 {
+			var ok bool
+			if mapFn, ok = args[0].(Function); !ok {
+				return nil, fmt.Errorf("expected query argument, received %T", args[0])
+			}
+		}
+
+		return ClosureFunction("method "+name, func(ctx FunctionContext) (interface{}, error) {
+			v, err := target.Exec(ctx)
+			if err != nil {
+				return nil, err
+			}
+			values, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeErrorFrom(target.Annotation(), v, ValueArray)
+			}
+
+			h := &rankedHeap{largest: largest}
+			best := rankedElement{index: -1}
+			for i, ele := range values {
+				r, isString, err := rankOf(ctx, name, mapFn, i, ele)
+				if err != nil {
+					return nil, err
+				}
+				if i == 0 {
+					h.isString, best = isString, r
+					continue
+				}
+				if err := checkRankType(name, i, h.isString, isString); err != nil {
+					return nil, err
+				}
+				if h.better(r, best) {
+					best = r
+				}
+			}
+			return int64(best.index), nil
+		}, aggregateTargetPaths(target, mapFn)), nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
 var _ = registerSimpleMethod(
 	NewMethodSpec(
 		"slice", "",
@@ -1344,6 +2557,130 @@ func sumMethod(target Function, _ ...interface{}) (Function, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"take_while", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Executes a query argument for each element of an array in order and returns the leading elements for which it returns `true`, stopping at the first element for which it doesn't. As with [`filter`](#filter) any non-boolean result is treated as `false`. The remaining elements can be obtained with [`drop_while`](#drop_while).",
+		NewExampleSpec("",
+			`root.prefix = this.items.take_while(i -> i < 10)`,
+			`{"items":[3,7,12,4,15]}`,
+			`{"prefix":[3,7]}`,
+		),
+	),
+	whileMethod(true),
+	false,
+	ExpectNArgs(1),
+	ExpectFunctionArg(0),
+)
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"drop_while", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Executes a query argument for each element of an array in order and removes the leading elements for which it returns `true`, returning all elements from the first for which it doesn't. As with [`filter`](#filter) any non-boolean result is treated as `false`. The removed elements can be obtained with [`take_while`](#take_while).",
+		NewExampleSpec("",
+			`root.rest = this.items.drop_while(i -> i < 10)`,
+			`{"items":[3,7,12,4,15]}`,
+			`{"rest":[12,4,15]}`,
+		),
+	),
+	whileMethod(false),
+	false,
+	ExpectNArgs(1),
+	ExpectFunctionArg(0),
+)
+
+func whileMethod(take bool) func(args ...interface{}) (simpleMethod, error) {
+	return func(args ...interface{}) (simpleMethod, error) {
+		mapFn, ok := args[0].(Function)
+		if !ok {
+			return nil, fmt.Errorf("expected query argument, received %T", args[0])
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			values, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			i := 0
+			for ; i < len(values); i++ {
+				f, err := mapFn.Exec(ctx.WithValue(values[i]))
+				if err != nil {
+					return nil, err
+				}
+				if b, _ := f.(bool); !b {
+					break
+				}
+			}
+			var result []interface{}
+			if take {
+				result = make([]interface{}, i)
+				copy(result, values[:i])
+			} else {
+				result = make([]interface{}, len(values)-i)
+				copy(result, values[i:])
+			}
+			return result, nil
+		}, nil
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"transpose", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Transposes an array of equal length arrays, such that the rows of the input become the columns of the output. This is useful for converting tabular data between row-major and column-major layouts. An error is returned if the rows are not all of the same length.",
+		NewExampleSpec("",
+			`root.columns = this.rows.transpose()`,
+			`{"rows":[["a",1,true],["b",2,false]]}`,
+			`{"columns":[["a","b"],[1,2],[true,false]]}`,
+		),
+	),
+	func(...interface{}) (simpleMethod, error) {
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			rows, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			if len(rows) == 0 {
+				return []interface{}{}, nil
+			}
+
+			width := -1
+			for i, row := range rows {
+				rowArr, ok := row.([]interface{})
+				if !ok {
+					return nil, fmt.Errorf("row %v: %w", i, NewTypeError(row, ValueArray))
+				}
+				if width == -1 {
+					width = len(rowArr)
+				} else if len(rowArr) != width {
+					return nil, fmt.Errorf("row %v has %v elements, expected %v", i, len(rowArr), width)
+				}
+			}
+
+			columns := make([]interface{}, width)
+			for j := range columns {
+				column := make([]interface{}, len(rows))
+				for i, row := range rows {
+					column[i] = row.([]interface{})[j]
+				}
+				columns[j] = column
+			}
+			return columns, nil
+		}, nil
+	},
+	false,
+	ExpectNArgs(0),
+)
+
+//------------------------------------------------------------------------------
+
 var _ = registerSimpleMethod(
 	NewMethodSpec(
 		"unique", "",
@@ -1449,6 +2786,120 @@ func uniqueMethod(args ...interface{}) (simpleMethod, error) {
 
 //------------------------------------------------------------------------------
 
+var _ = registerMethod(
+	NewMethodSpec(
+		"dedupe_consecutive", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Removes elements of an array that are structurally equal to the element before them, such that each run of adjacent duplicates is reduced to its first element. Unlike [`unique`](#unique) duplicates that are not adjacent are kept. An optional query argument can be provided in order to compare elements by a value derived from them rather than the elements themselves.",
+		NewExampleSpec("",
+			`root.events = this.events.dedupe_consecutive()`,
+			`{"events":["up","up","down","down","down","up"]}`,
+			`{"events":["up","down","up"]}`,
+		),
+		NewExampleSpec("",
+			`root.events = this.events.dedupe_consecutive(e -> e.state)`,
+			`{"events":[{"state":"up","ts":1},{"state":"up","ts":2},{"state":"down","ts":3},{"state":"up","ts":4}]}`,
+			`{"events":[{"state":"up","ts":1},{"state":"down","ts":3},{"state":"up","ts":4}]}`,
+		),
+	),
+	false, dedupeConsecutiveMethod,
+	ExpectOneOrZeroArgs(),
+)
+
+// structurallyEqual returns whether two values are equal, where numbers of
+// different types are compared by value and arrays and objects are compared
+// recursively.
+func structurallyEqual(lhs, rhs interface{}) bool {
+	lhs, rhs = restrictForComparison(lhs), restrictForComparison(rhs)
+	switch l := lhs.(type) {
+	case []interface{}:
+		r, ok := rhs.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !structurallyEqual(l[i], r[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		r, ok := rhs.(map[string]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for k, lv := range l {
+			rv, exists := r[k]
+			if !exists || !structurallyEqual(lv, rv) {
+				return false
+			}
+		}
+		return true
+	}
+	return cmp.Equal(lhs, rhs)
+}
+
+func dedupeConsecutiveMethod(target Function, args ...interface{}) (Function, error) {
+	var mapFn Function
+	if len(args) > 0 {
+		var ok bool
+		if mapFn, ok = args[0].(Function); !ok {
+			return nil, fmt.Errorf("expected query argument, received %T", args[0])
+		}
+	}
+
+	return ClosureFunction("method dedupe_consecutive", func(ctx FunctionContext) (interface{}, error) {
+		v, err := target.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		values, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeErrorFrom(target.Annotation(), v, ValueArray)
+		}
+
+		result := make([]interface{}, 0, len(values))
+		var lastKey interface{}
+		for i, ele := range values {
+			key := ele
+			if mapFn != nil {
+				if key, err = mapFn.Exec(ctx.WithValue(ele)); err != nil {
+					return nil, fmt.Errorf("index %v: %w", i, err)
+				}
+			}
+			if i > 0 && structurallyEqual(lastKey, key) {
+				continue
+			}
+			result = append(result, ele)
+			lastKey = key
+		}
+		return result, nil
+	}, aggregateTargetPaths(target, mapFn)), nil
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"unique_by", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Removes elements of an array that share a key with an earlier element, where the key of each element is emitted by a query argument. Only the first element with each key is kept and the order of elements is preserved. Keys must be either strings or numbers, and as with [`unique`](#unique) numbers and strings are checked separately.",
+		NewExampleSpec("",
+			`root.users = this.users.unique_by(u -> u.email)`,
+			`{"users":[{"email":"a@example.com","name":"Ash"},{"email":"b@example.com","name":"Bo"},{"email":"a@example.com","name":"Ashley"}]}`,
+			`{"users":[{"email":"a@example.com","name":"Ash"},{"email":"b@example.com","name":"Bo"}]}`,
+		),
+	),
+	uniqueMethod,
+	false,
+	ExpectNArgs(1),
+	ExpectFunctionArg(0),
+)
+
+//------------------------------------------------------------------------------
+
 var _ = registerSimpleMethod(
 	NewMethodSpec(
 		"values", "",
@@ -1542,3 +2993,240 @@ func mapWithout(m map[string]interface{}, paths [][]string) map[string]interface
 }
 
 //------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"redact_paths", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns a copy of a structured value where the values at each of an array of [field paths][field_paths] are replaced with a placeholder, preserving the structure of the document. A path segment of `*` matches all elements of an array or all values of an object. Paths that do not exist are ignored. The placeholder defaults to the string `[REDACTED]` and can be changed with an optional second argument.",
+		NewExampleSpec("",
+			`root = this.redact_paths(["user.password","items.*.secret","tokens"])`,
+			`{"items":[{"id":1,"secret":"a"},{"id":2}],"tokens":["b","c"],"user":{"name":"ash","password":"hunter2"}}`,
+			`{"items":[{"id":1,"secret":"[REDACTED]"},{"id":2}],"tokens":"[REDACTED]","user":{"name":"ash","password":"[REDACTED]"}}`,
+		),
+		NewExampleSpec("",
+			`root = this.redact_paths(["user.password"], null)`,
+			`{"user":{"name":"ash","password":"hunter2"}}`,
+			`{"user":{"name":"ash","password":null}}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		pathsArr, ok := args[0].([]interface{})
+		if !ok {
+			return nil, NewTypeError(args[0], ValueArray)
+		}
+		paths := make([][]string, 0, len(pathsArr))
+		for i, p := range pathsArr {
+			pStr, err := IGetString(p)
+			if err != nil {
+				return nil, fmt.Errorf("path %v: %w", i, err)
+			}
+			paths = append(paths, gabs.DotPathToSlice(pStr))
+		}
+		var placeholder interface{} = "[REDACTED]"
+		if len(args) > 1 {
+			placeholder = args[1]
+		}
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			switch v.(type) {
+			case map[string]interface{}, []interface{}:
+			default:
+				return nil, NewTypeError(v, ValueObject, ValueArray)
+			}
+			v = IClone(v)
+			for _, p := range paths {
+				redactPath(v, p, placeholder)
+			}
+			return v, nil
+		}, nil
+	},
+	true,
+	ExpectBetweenNAndMArgs(1, 2),
+)
+
+func redactPath(v interface{}, path []string, placeholder interface{}) {
+	if len(path) == 0 {
+		return
+	}
+	key, tail := path[0], path[1:]
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if key != "*" && key != k {
+				continue
+			}
+			if len(tail) == 0 {
+				t[k] = IClone(placeholder)
+			} else {
+				redactPath(child, tail, placeholder)
+			}
+		}
+	case []interface{}:
+		for i, child := range t {
+			if key != "*" && key != strconv.Itoa(i) {
+				continue
+			}
+			if len(tail) == 0 {
+				t[i] = IClone(placeholder)
+			} else {
+				redactPath(child, tail, placeholder)
+			}
+		}
+	}
+}
+
+//------------------------------------------------------------------------------
+
+var _ = registerSimpleMethod(
+	NewMethodSpec(
+		"rotate", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns a new array with the elements of an array rotated left by a number of positions, such that elements shifted off the start are moved to the end. A negative number rotates the array right instead. The number of positions is taken modulo the length of the array, so rotating by the length of the array returns it unchanged.",
+		NewExampleSpec("",
+			`root.left = this.items.rotate(1)
+root.right = this.items.rotate(-1)`,
+			`{"items":["a","b","c","d"]}`,
+			`{"left":["b","c","d","a"],"right":["d","a","b","c"]}`,
+		),
+	),
+	func(args ...interface{}) (simpleMethod, error) {
+		n := args[0].(int64)
+		return func(v interface{}, ctx FunctionContext) (interface{}, error) {
+			values, ok := v.([]interface{})
+			if !ok {
+				return nil, NewTypeError(v, ValueArray)
+			}
+			result := make([]interface{}, 0, len(values))
+			if len(values) == 0 {
+				return result, nil
+			}
+			offset := int(n % int64(len(values)))
+			if offset < 0 {
+				offset += len(values)
+			}
+			result = append(result, values[offset:]...)
+			return append(result, values[:offset]...), nil
+		}, nil
+	},
+	true,
+	ExpectNArgs(1),
+	ExpectIntArg(0),
+)
+
+//------------------------------------------------------------------------------
+
+var _ = registerMethod(
+	NewMethodSpec(
+		"sample", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns an array containing up to N elements of the target array chosen at random without replacement. If N exceeds the length of the array then all elements are returned in a random order. An optional second argument can be provided in order to seed the random number generator, following the same behaviour as the [`random_int`](/docs/guides/bloblang/functions#random_int) function.",
+		NewExampleSpec("",
+			`root.picked = this.items.sample(2, 10)`,
+			`{"items":["a","b","c","d","e"]}`,
+			`{"picked":["e","b"]}`,
+		),
+	),
+	false, sampleMethod,
+	ExpectBetweenNAndMArgs(1, 2),
+	ExpectFunctionArg(0),
+)
+
+func sampleMethod(target Function, args ...interface{}) (Function, error) {
+	countFn := args[0].(Function)
+	var seed interface{}
+	if len(args) > 1 {
+		seed = args[1]
+	}
+	r, err := newSeededRand(seed)
+	if err != nil {
+		return nil, err
+	}
+	return ClosureFunction("method sample", func(ctx FunctionContext) (interface{}, error) {
+		v, err := target.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeErrorFrom(target.Annotation(), v, ValueArray)
+		}
+
+		countV, err := countFn.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		count, err := IGetInt(countV)
+		if err != nil {
+			return nil, ErrFrom(err, countFn)
+		}
+		if count < 0 {
+			return nil, fmt.Errorf("sample count must be non-negative, got %v", count)
+		}
+		if count > int64(len(arr)) {
+			count = int64(len(arr))
+		}
+
+		copied := make([]interface{}, len(arr))
+		copy(copied, arr)
+		if err = r.with(ctx, func(r *rand.Rand) {
+			for i := 0; i < int(count); i++ {
+				j := i + r.Intn(len(copied)-i)
+				copied[i], copied[j] = copied[j], copied[i]
+			}
+		}); err != nil {
+			return nil, err
+		}
+		return copied[:count], nil
+	}, aggregateTargetPaths(target, countFn)), nil
+}
+
+var _ = registerMethod(
+	NewMethodSpec(
+		"shuffle", "",
+	).InCategory(
+		MethodCategoryObjectAndArray,
+		"Returns a copy of an array with the elements in a random order. The target array is not modified. An optional argument can be provided in order to seed the random number generator, following the same behaviour as the [`random_int`](/docs/guides/bloblang/functions#random_int) function.",
+		NewExampleSpec("",
+			`root.shuffled = this.items.shuffle(10)`,
+			`{"items":["a","b","c","d","e"]}`,
+			`{"shuffled":["d","a","e","b","c"]}`,
+		),
+	),
+	false, shuffleMethod,
+	ExpectOneOrZeroArgs(),
+)
+
+func shuffleMethod(target Function, args ...interface{}) (Function, error) {
+	var seed interface{}
+	if len(args) > 0 {
+		seed = args[0]
+	}
+	r, err := newSeededRand(seed)
+	if err != nil {
+		return nil, err
+	}
+	return ClosureFunction("method shuffle", func(ctx FunctionContext) (interface{}, error) {
+		v, err := target.Exec(ctx)
+		if err != nil {
+			return nil, err
+		}
+		arr, ok := v.([]interface{})
+		if !ok {
+			return nil, NewTypeErrorFrom(target.Annotation(), v, ValueArray)
+		}
+
+		copied := make([]interface{}, len(arr))
+		copy(copied, arr)
+		if err = r.with(ctx, func(r *rand.Rand) {
+			r.Shuffle(len(copied), func(i, j int) {
+				copied[i], copied[j] = copied[j], copied[i]
+			})
+		}); err != nil {
+			return nil, err
+		}
+		return copied, nil
+	}, target.QueryTargets), nil
+}
@@ -45,6 +45,70 @@ func TestMethodImmutability(t *testing.T) {
 				"baz": "buz",
 			},
 		},
+		{
+			name:   "interpolate missing",
+			method: "interpolate_missing",
+			target: []interface{}{1.0, nil, 3.0},
+			args:   []interface{}{},
+			exp:    []interface{}{1.0, 2.0, 3.0},
+		},
+		{
+			name:   "top k",
+			method: "top_k",
+			target: []interface{}{3.0, 9.0, 1.0, 12.0},
+			args:   []interface{}{int64(2)},
+			exp:    []interface{}{12.0, 9.0},
+		},
+		{
+			name:   "bottom k",
+			method: "bottom_k",
+			target: []interface{}{3.0, 9.0, 1.0, 12.0},
+			args:   []interface{}{int64(2)},
+			exp:    []interface{}{1.0, 3.0},
+		},
+		{
+			name:   "set existing path",
+			method: "set",
+			target: map[string]interface{}{
+				"foo": map[string]interface{}{"bar": "baz"},
+				"buz": []interface{}{"a", "b"},
+			},
+			args: []interface{}{"foo.bar", map[string]interface{}{"new": "value"}},
+			exp: map[string]interface{}{
+				"foo": map[string]interface{}{"bar": map[string]interface{}{"new": "value"}},
+				"buz": []interface{}{"a", "b"},
+			},
+		},
+		{
+			name:   "set array path",
+			method: "set",
+			target: map[string]interface{}{
+				"buz": []interface{}{"a", "b"},
+			},
+			args: []interface{}{"buz.2", "c"},
+			exp: map[string]interface{}{
+				"buz": []interface{}{"a", "b", "c"},
+			},
+		},
+		{
+			name:   "redact paths",
+			method: "redact_paths",
+			target: map[string]interface{}{
+				"foo": map[string]interface{}{"bar": "secret", "baz": "public"},
+				"buz": []interface{}{
+					map[string]interface{}{"bar": "secret"},
+				},
+			},
+			args: []interface{}{
+				[]interface{}{"foo.bar", "buz.*.bar"},
+			},
+			exp: map[string]interface{}{
+				"foo": map[string]interface{}{"bar": "[REDACTED]", "baz": "public"},
+				"buz": []interface{}{
+					map[string]interface{}{"bar": "[REDACTED]"},
+				},
+			},
+		},
 	}
 
 	for _, test := range testCases {
@@ -69,3 +133,105 @@ func TestMethodImmutability(t *testing.T) {
 		})
 	}
 }
+
+func TestMethodSample(t *testing.T) {
+	input := []interface{}{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	execN := func(fn Function, n int) [][]interface{} {
+		t.Helper()
+		var results [][]interface{}
+		for i := 0; i < n; i++ {
+			res, err := fn.Exec(FunctionContext{})
+			require.NoError(t, err)
+			results = append(results, res.([]interface{}))
+		}
+		return results
+	}
+
+	fnA, err := InitMethod("sample", NewLiteralFunction("", input), int64(3), int64(10))
+	require.NoError(t, err)
+	fnB, err := InitMethod("sample", NewLiteralFunction("", input), int64(3), int64(10))
+	require.NoError(t, err)
+
+	resA, resB := execN(fnA, 20), execN(fnB, 20)
+	assert.Equal(t, resA, resB, "same seed should produce the same samples")
+
+	for _, r := range resA {
+		require.Len(t, r, 3)
+		seen := map[interface{}]struct{}{}
+		for _, v := range r {
+			assert.Contains(t, input, v)
+			seen[v] = struct{}{}
+		}
+		assert.Len(t, seen, 3, "elements must be chosen without replacement")
+	}
+
+	fnC, err := InitMethod("sample", NewLiteralFunction("", input), int64(3), int64(11))
+	require.NoError(t, err)
+	assert.NotEqual(t, resA, execN(fnC, 20))
+
+	assert.Equal(t, []interface{}{"a", "b", "c", "d", "e", "f", "g", "h"}, input)
+}
+
+func TestMethodSampleExceedsLength(t *testing.T) {
+	input := []interface{}{"a", "b", "c"}
+
+	fn, err := InitMethod("sample", NewLiteralFunction("", input), int64(10), int64(1))
+	require.NoError(t, err)
+
+	res, err := fn.Exec(FunctionContext{})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, input, res)
+
+	fn, err = InitMethod("sample", NewLiteralFunction("", []interface{}{}), int64(2))
+	require.NoError(t, err)
+
+	res, err = fn.Exec(FunctionContext{})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{}, res)
+
+	fn, err = InitMethod("sample", NewLiteralFunction("", input), int64(-1))
+	require.NoError(t, err)
+
+	_, err = fn.Exec(FunctionContext{})
+	require.EqualError(t, err, "sample count must be non-negative, got -1")
+}
+
+func TestMethodShuffle(t *testing.T) {
+	input := []interface{}{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	fnA, err := InitMethod("shuffle", NewLiteralFunction("", input), int64(5))
+	require.NoError(t, err)
+	fnB, err := InitMethod("shuffle", NewLiteralFunction("", input), int64(5))
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		resA, err := fnA.Exec(FunctionContext{})
+		require.NoError(t, err)
+		resB, err := fnB.Exec(FunctionContext{})
+		require.NoError(t, err)
+
+		assert.Equal(t, resA, resB, "same seed should produce the same permutation")
+		assert.ElementsMatch(t, input, resA)
+	}
+
+	assert.Equal(t, []interface{}{"a", "b", "c", "d", "e", "f", "g", "h"}, input)
+}
+
+func TestMethodShuffleDynamicSeed(t *testing.T) {
+	input := []interface{}{"a", "b", "c", "d", "e", "f", "g", "h"}
+
+	fnA, err := InitMethod("shuffle", NewLiteralFunction("", input), NewFieldFunction("seed"))
+	require.NoError(t, err)
+	fnB, err := InitMethod("shuffle", NewLiteralFunction("", input), int64(7))
+	require.NoError(t, err)
+
+	ctx := FunctionContext{}.WithValue(map[string]interface{}{"seed": int64(7)})
+	for i := 0; i < 5; i++ {
+		resA, err := fnA.Exec(ctx)
+		require.NoError(t, err)
+		resB, err := fnB.Exec(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, resA, resB)
+	}
+}
@@ -61,6 +61,14 @@ type FunctionContext struct {
 	// Reference new message being mapped
 	NewMsg types.Part
 
+	// State that persists across executions of a mapping, which is nil when
+	// a query is executed outside of a mapping.
+	Shared *SharedState
+
+	// State that is discarded at the end of each execution of a mapping, which
+	// is nil when a query is executed outside of a mapping.
+	ExecShared *SharedState
+
 	valueFn    func() *interface{}
 	value      *interface{}
 	nextValue  *interface{}
@@ -0,0 +1,63 @@
+package query
+
+import "sync"
+
+// SharedState holds values that are shared between the queries of a mapping.
+// A mapping creates a SharedState when it is parsed and provides it to queries
+// through the field Shared of each FunctionContext, such as for the named bloom
+// filters of the methods bloom_add and bloom_test, which means that state is
+// scoped to the lifetime of the mapping and is never shared between separate
+// mappings. A mapping also provides a SharedState through the field ExecShared
+// that is discarded at the end of each execution, such as for the results of
+// the function memoize.
+type SharedState struct {
+	mut    sync.Mutex
+	values map[interface{}]interface{}
+}
+
+// NewSharedState creates an empty SharedState.
+func NewSharedState() *SharedState {
+	return &SharedState{}
+}
+
+// Get returns the value stored under a key, and whether it exists.
+func (s *SharedState) Get(key interface{}) (interface{}, bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	v, exists := s.values[key]
+	return v, exists
+}
+
+// Set stores a value under a key, replacing any existing value.
+func (s *SharedState) Set(key, value interface{}) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.values == nil {
+		s.values = map[interface{}]interface{}{}
+	}
+	s.values[key] = value
+}
+
+// GetOrCreate returns the value stored under a key, or if the key does not
+// exist calls a constructor and stores the value it returns. If the constructor
+// returns an error then nothing is stored. The constructor is called whilst the
+// state is locked and must therefore not access the state itself.
+func (s *SharedState) GetOrCreate(key interface{}, ctor func() (interface{}, error)) (interface{}, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if v, exists := s.values[key]; exists {
+		return v, nil
+	}
+	v, err := ctor()
+	if err != nil {
+		return nil, err
+	}
+	if s.values == nil {
+		s.values = map[interface{}]interface{}{}
+	}
+	s.values[key] = v
+	return v, nil
+}
@@ -132,6 +132,7 @@ root.doc.host = hostname()
 [field_paths]: /docs/configuration/field_paths
 [meta_proc]: /docs/components/processors/metadata
 [methods.encode]: /docs/guides/bloblang/methods#encode
+[methods.from]: /docs/guides/bloblang/methods#from
 [methods.string]: /docs/guides/bloblang/methods#string
 `
 
@@ -290,6 +291,7 @@ func BloblangMethodsMarkdown() ([]byte, error) {
 		query.MethodCategoryObjectAndArray,
 		query.MethodCategoryParsing,
 		query.MethodCategoryEncoding,
+		query.MethodCategoryDeduplication,
 		query.MethodCategoryDeprecated,
 	} {
 		methods := methodCategory{
@@ -43,6 +43,34 @@ func TestEnvironment(t *testing.T) {
 	assert.Equal(t, "bar", v)
 }
 
+func TestEnvironmentReplaceMethod(t *testing.T) {
+	ctor := func(_ ...interface{}) (Method, error) {
+		return ArrayMethod(func(in []interface{}) (interface{}, error) {
+			return len(in), nil
+		}), nil
+	}
+
+	// Builtin methods cannot be overridden.
+	assert.EqualError(t, RegisterMethod("shuffle", ctor), "conflicting method name: shuffle")
+
+	env := NewEnvironment().WithoutMethods("shuffle")
+	require.NoError(t, env.RegisterMethod("shuffle", ctor))
+
+	exe, err := env.Parse(`root = this.shuffle()`)
+	require.NoError(t, err)
+
+	v, err := exe.Query([]interface{}{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, 3, v)
+
+	exe, err = Parse(`root = this.shuffle(1).length()`)
+	require.NoError(t, err)
+
+	v, err = exe.Query([]interface{}{"a", "b", "c"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), v)
+}
+
 func TestEmptyEnvironment(t *testing.T) {
 	env := NewEmptyEnvironment()
 
@@ -30,7 +30,7 @@ func Example_bloblangPlugins() {
 		panic(err)
 	}
 
-	if err := bloblang.RegisterMethod("shuffle", func(_ ...interface{}) (bloblang.Method, error) {
+	if err := bloblang.RegisterMethod("jumble", func(_ ...interface{}) (bloblang.Method, error) {
 		rand := rand.New(rand.NewSource(0))
 		return bloblang.ArrayMethod(func(in []interface{}) (interface{}, error) {
 			out := make([]interface{}, len(in))
@@ -63,7 +63,7 @@ func Example_bloblangPlugins() {
 
 	mapping := `
 root.new_summary = this.summary.cuddle("meow", "woof")
-root.shuffled = this.names.shuffle()
+root.jumbled = this.names.jumble()
 root.num = add_but_always_slightly_wrong(1.2, 2.6)
 `
 
@@ -86,7 +86,7 @@ root.num = add_but_always_slightly_wrong(1.2, 2.6)
 	}
 
 	fmt.Println(string(jsonBytes))
-	// Output: {"new_summary":"meowquackwoof","num":3.82,"shuffled":["olaf","jen","pixie","denny","spuz"]}
+	// Output: {"jumbled":["olaf","jen","pixie","denny","spuz"],"new_summary":"meowquackwoof","num":3.82}
 }
 
 // This example demonstrates how to create and use an isolated Bloblang